@@ -0,0 +1,259 @@
+package sns
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultConfirmationQueueSize is the number of confirmations that can wait for
+// the background worker.
+const DefaultConfirmationQueueSize = 64
+
+const (
+	confirmationStatusTTL   = time.Hour
+	maxConfirmationStatuses = 1024
+)
+
+var (
+	ErrNotFoundConfirmationStatus = errors.New("not found confirmation status")
+	ErrConfirmationQueueFull      = errors.New("error confirmation queue full")
+	ErrShutdown                   = errors.New("error middleware shut down")
+)
+
+type ConfirmationState int
+
+const (
+	ConfirmationQueued ConfirmationState = iota + 1
+	ConfirmationSucceeded
+	ConfirmationFailed
+)
+
+func (s ConfirmationState) String() string {
+	switch s {
+	case ConfirmationQueued:
+		return "Queued"
+	case ConfirmationSucceeded:
+		return "Succeeded"
+	case ConfirmationFailed:
+		return "Failed"
+	default:
+		return "Unknown"
+	}
+}
+
+// ConfirmationStatus is the outcome of an asynchronous confirmation.
+type ConfirmationStatus struct {
+	TopicArn  string
+	MessageId string
+	State     ConfirmationState
+	Attempts  int
+	Result    *ConfirmSubscriptionResult
+	Err       error
+	UpdatedAt time.Time
+}
+
+// WithAsyncConfirmation answers SubscriptionConfirmation requests with 200 right
+// away and confirms them in a background worker, retrying failed attempts with p
+// instead of the retry policy of the Client. An *APIError is retried according
+// to p.Retryable for its status code; missing credentials are not retried.
+// The middleware answers 503 when the queue is full so that SNS retries the delivery.
+// Call Middleware.Shutdown to drain the queue.
+func WithAsyncConfirmation(p RetryPolicy) Option {
+	return func(m *Middleware) {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.async = &asyncConfirmer{
+			policy:   p,
+			queue:    make(chan SubscriptionConfirmation, DefaultConfirmationQueueSize),
+			statuses: map[string]ConfirmationStatus{},
+			ctx:      ctx,
+			cancel:   cancel,
+			stop:     make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+	}
+}
+
+// WithOnConfirmationComplete is called with the outcome of every asynchronous confirmation.
+func WithOnConfirmationComplete(f func(token string, status ConfirmationStatus)) Option {
+	return func(m *Middleware) {
+		m.onConfirmationComplete = append(m.onConfirmationComplete, f)
+	}
+}
+
+// ConfirmationStatus returns the status of the asynchronous confirmation with
+// token. Statuses are kept for an hour after the confirmation is done, and
+// only the latest 1024 of them.
+func (m *Middleware) ConfirmationStatus(token string) (ConfirmationStatus, error) {
+	if m.async == nil {
+		return ConfirmationStatus{}, ErrNotFoundConfirmationStatus
+	}
+	return m.async.status(token)
+}
+
+// Shutdown stops accepting asynchronous confirmations and retrying failed ones,
+// and waits until the queued ones are attempted or ctx is done. The confirmation
+// in progress is canceled when ctx is done.
+func (m *Middleware) Shutdown(ctx context.Context) error {
+	if m.async == nil {
+		return nil
+	}
+	m.async.close()
+
+	select {
+	case <-m.async.done:
+		return nil
+	case <-ctx.Done():
+		m.async.cancel()
+		return ctx.Err()
+	}
+}
+
+type asyncConfirmer struct {
+	policy RetryPolicy
+	queue  chan SubscriptionConfirmation
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu       sync.Mutex
+	closed   bool
+	statuses map[string]ConfirmationStatus
+	order    []string
+}
+
+func (a *asyncConfirmer) enqueue(msg SubscriptionConfirmation) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return ErrShutdown
+	}
+	select {
+	case a.queue <- msg:
+	default:
+		return ErrConfirmationQueueFull
+	}
+	now := time.Now()
+	a.evict(now)
+	if _, ok := a.statuses[msg.Token]; !ok {
+		a.order = append(a.order, msg.Token)
+	}
+	a.statuses[msg.Token] = ConfirmationStatus{
+		TopicArn:  msg.TopicArn,
+		MessageId: msg.MessageId,
+		State:     ConfirmationQueued,
+		UpdatedAt: now,
+	}
+	return nil
+}
+
+// evict removes the oldest statuses of finished confirmations once they are
+// older than confirmationStatusTTL or there are more than
+// maxConfirmationStatuses of them.
+func (a *asyncConfirmer) evict(now time.Time) {
+	for len(a.order) > 0 {
+		token := a.order[0]
+		if s, ok := a.statuses[token]; ok {
+			if s.State == ConfirmationQueued {
+				return
+			}
+			if len(a.statuses) < maxConfirmationStatuses && now.Sub(s.UpdatedAt) < confirmationStatusTTL {
+				return
+			}
+			delete(a.statuses, token)
+		}
+		a.order = a.order[1:]
+	}
+}
+
+func (a *asyncConfirmer) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+		close(a.stop)
+	}
+}
+
+func (a *asyncConfirmer) status(token string) (ConfirmationStatus, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.statuses[token]
+	if !ok {
+		return ConfirmationStatus{}, ErrNotFoundConfirmationStatus
+	}
+	return s, nil
+}
+
+func (a *asyncConfirmer) setStatus(token string, s ConfirmationStatus) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.statuses[token] = s
+}
+
+func (m *Middleware) runAsyncConfirmer() {
+	defer close(m.async.done)
+	defer m.async.cancel()
+
+	for msg := range m.async.queue {
+		s := m.confirmWithRetry(msg)
+		m.async.setStatus(msg.Token, s)
+		for _, f := range m.onConfirmationComplete {
+			f(msg.Token, s)
+		}
+	}
+}
+
+func (m *Middleware) confirmWithRetry(msg SubscriptionConfirmation) ConfirmationStatus {
+	p := m.async.policy
+	ctx := withoutRetry(m.async.ctx)
+	s := ConfirmationStatus{
+		TopicArn:  msg.TopicArn,
+		MessageId: msg.MessageId,
+	}
+	for {
+		s.Attempts++
+		s.Result, s.Err = m.confirm(ctx, msg)
+		if s.Err == nil {
+			s.State = ConfirmationSucceeded
+			break
+		}
+		if s.Attempts >= p.MaxAttempts || !p.retryableError(s.Err) {
+			s.State = ConfirmationFailed
+			break
+		}
+
+		e := RetryEvent{
+			Op:      OpConfirmSubscription,
+			Attempt: s.Attempts,
+			Delay:   p.backoff(s.Attempts - 1),
+			Err:     s.Err,
+		}
+		var apiErr *APIError
+		if errors.As(s.Err, &apiErr) {
+			e.StatusCode = apiErr.StatusCode
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(e)
+		}
+
+		t := time.NewTimer(e.Delay)
+		select {
+		case <-t.C:
+			continue
+		case <-m.async.stop:
+			t.Stop()
+		}
+		s.State = ConfirmationFailed
+		break
+	}
+	s.UpdatedAt = time.Now()
+	return s
+}
@@ -0,0 +1,309 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMiddleware_Subscribe_AsyncConfirmation(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	msg := SubscriptionConfirmation{
+		Type:      "SubscriptionConfirmation",
+		MessageId: "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+		Token:     "Ethevee8dae4mie3",
+		TopicArn:  topicARN,
+		Timestamp: mustParseTimestamp("2012-04-26T20:45:04.751Z"),
+	}
+
+	tests := map[string]struct {
+		failures     int
+		err          error
+		maxAttempts  int
+		wantState    ConfirmationState
+		wantAttempts int
+		wantRetries  int
+	}{
+		"success": {
+			failures:     0,
+			maxAttempts:  3,
+			wantState:    ConfirmationSucceeded,
+			wantAttempts: 1,
+			wantRetries:  0,
+		},
+		"retry_then_success": {
+			failures:     2,
+			maxAttempts:  3,
+			wantState:    ConfirmationSucceeded,
+			wantAttempts: 3,
+			wantRetries:  2,
+		},
+		"failed": {
+			failures:     3,
+			maxAttempts:  2,
+			wantState:    ConfirmationFailed,
+			wantAttempts: 2,
+			wantRetries:  1,
+		},
+		"throttled_then_success": {
+			failures:     1,
+			err:          &APIError{StatusCode: http.StatusTooManyRequests, Code: "Throttling"},
+			maxAttempts:  3,
+			wantState:    ConfirmationSucceeded,
+			wantAttempts: 2,
+			wantRetries:  1,
+		},
+		"not_retryable_api_error": {
+			failures:     5,
+			err:          &APIError{StatusCode: http.StatusBadRequest, Code: "InvalidParameter"},
+			maxAttempts:  5,
+			wantState:    ConfirmationFailed,
+			wantAttempts: 1,
+			wantRetries:  0,
+		},
+		"authenticated_confirmation_unsupported": {
+			failures:     5,
+			err:          ErrAuthenticatedConfirmationUnsupported,
+			maxAttempts:  5,
+			wantState:    ConfirmationFailed,
+			wantAttempts: 1,
+			wantRetries:  0,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				mu        sync.Mutex
+				retries   int
+				completed []ConfirmationStatus
+			)
+			release := make(chan struct{})
+			finished := make(chan struct{})
+			m := NewMiddleware(
+				WithAsyncConfirmation(RetryPolicy{
+					MaxAttempts: tt.maxAttempts,
+					BaseDelay:   time.Millisecond,
+					MaxDelay:    time.Millisecond,
+					OnRetry: func(e RetryEvent) {
+						mu.Lock()
+						defer mu.Unlock()
+						retries++
+					},
+				}),
+				WithOnConfirmationComplete(func(token string, s ConfirmationStatus) {
+					mu.Lock()
+					defer mu.Unlock()
+					completed = append(completed, s)
+					close(finished)
+				}),
+			)
+			calls := 0
+			setSubscriber(m, &mockSubscriber{
+				ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+					<-release
+					calls++
+					if calls <= tt.failures {
+						if tt.err != nil {
+							return nil, tt.err
+						}
+						return nil, ErrConfirmSubscription
+					}
+					return &ConfirmSubscriptionResult{SubscriptionArn: topicARN + ":1"}, nil
+				},
+				ExpectValidateCertURL: func(certURL string) error {
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return nil
+				},
+			})
+
+			b, _ := json.Marshal(msg)
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, "SubscriptionConfirmation")
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Subscribe() = %v, want %v", w.Code, http.StatusOK)
+			}
+			s, err := m.ConfirmationStatus(msg.Token)
+			if err != nil {
+				t.Fatalf("ConfirmationStatus() error = %v", err)
+			}
+			if s.State != ConfirmationQueued {
+				t.Errorf("State = %v, want %v", s.State, ConfirmationQueued)
+			}
+
+			close(release)
+			<-finished
+			if err := m.Shutdown(context.Background()); err != nil {
+				t.Fatalf("Shutdown() error = %v", err)
+			}
+
+			s, err = m.ConfirmationStatus(msg.Token)
+			if err != nil {
+				t.Fatalf("ConfirmationStatus() error = %v", err)
+			}
+			if s.State != tt.wantState {
+				t.Errorf("State = %v, want %v", s.State, tt.wantState)
+			}
+			if s.Attempts != tt.wantAttempts {
+				t.Errorf("Attempts = %v, want %v", s.Attempts, tt.wantAttempts)
+			}
+			if retries != tt.wantRetries {
+				t.Errorf("retries = %v, want %v", retries, tt.wantRetries)
+			}
+			if len(completed) != 1 || completed[0].State != tt.wantState {
+				t.Errorf("completed = %v, want one %v", completed, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Subscribe_AsyncConfirmation_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	m := NewMiddleware(WithAsyncConfirmation(NoRetry()))
+	setSubscriber(m, &mockSubscriber{
+		ExpectValidateCertURL: func(certURL string) error {
+			return nil
+		},
+		ExpectCheckSignature: func(ms MessageSignature) error {
+			return nil
+		},
+	})
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	b, _ := json.Marshal(SubscriptionConfirmation{Token: "Ethevee8dae4mie3", TopicArn: topicARN})
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+	req.Header.Set(XAmzSnsTopicArn, topicARN)
+	req.Header.Set(XAmzSnsMessageType, "SubscriptionConfirmation")
+	w := httptest.NewRecorder()
+	m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Subscribe() = %v, want %v", w.Code, http.StatusServiceUnavailable)
+	}
+	if _, err := m.ConfirmationStatus("Ethevee8dae4mie3"); !errors.Is(err, ErrNotFoundConfirmationStatus) {
+		t.Errorf("ConfirmationStatus() error = %v, want %v", err, ErrNotFoundConfirmationStatus)
+	}
+}
+
+func TestMiddleware_Shutdown_InterruptsRetry(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	retrying := make(chan struct{})
+	m := NewMiddleware(WithAsyncConfirmation(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Hour,
+		MaxDelay:    time.Hour,
+		OnRetry: func(e RetryEvent) {
+			close(retrying)
+		},
+	}))
+	setSubscriber(m, &mockSubscriber{
+		ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+			return nil, ErrConfirmSubscription
+		},
+	})
+
+	msg := SubscriptionConfirmation{Token: "Ethevee8dae4mie3", TopicArn: topicARN}
+	if err := m.async.enqueue(msg); err != nil {
+		t.Fatal(err)
+	}
+	<-retrying
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	s, err := m.ConfirmationStatus(msg.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.State != ConfirmationFailed || s.Attempts != 1 {
+		t.Errorf("ConfirmationStatus() = %v after %d attempts, want %v after 1", s.State, s.Attempts, ConfirmationFailed)
+	}
+}
+
+func TestAsyncConfirmer_evict(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	a := &asyncConfirmer{
+		queue:    make(chan SubscriptionConfirmation, 1),
+		statuses: map[string]ConfirmationStatus{},
+	}
+	for i := 0; i < maxConfirmationStatuses; i++ {
+		token := fmt.Sprint(i)
+		a.order = append(a.order, token)
+		a.statuses[token] = ConfirmationStatus{State: ConfirmationSucceeded, UpdatedAt: now}
+	}
+	a.statuses["0"] = ConfirmationStatus{State: ConfirmationFailed, UpdatedAt: now.Add(-2 * confirmationStatusTTL)}
+
+	if err := a.enqueue(SubscriptionConfirmation{Token: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.statuses) != maxConfirmationStatuses {
+		t.Errorf("len(statuses) = %v, want %v", len(a.statuses), maxConfirmationStatuses)
+	}
+	if _, err := a.status("0"); err != ErrNotFoundConfirmationStatus {
+		t.Errorf("status(0) error = %v, want %v", err, ErrNotFoundConfirmationStatus)
+	}
+	if s, err := a.status("new"); err != nil || s.State != ConfirmationQueued {
+		t.Errorf("status(new) = %v, %v", s, err)
+	}
+}
+
+func TestMiddleware_AsyncConfirmation_ClientRetry(t *testing.T) {
+	t.Parallel()
+
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := NewClient(WithHTTPClient(ts.Client()), WithRetryPolicy(RetryPolicy{MaxAttempts: 3}))
+	finished := make(chan ConfirmationStatus, 1)
+	m := NewMiddleware(
+		WithClient(c),
+		WithAsyncConfirmation(RetryPolicy{MaxAttempts: 2}),
+		WithOnConfirmationComplete(func(token string, s ConfirmationStatus) {
+			finished <- s
+		}),
+	)
+	if err := m.async.enqueue(SubscriptionConfirmation{Token: "Ethevee8dae4mie3", SubscribeURL: ts.URL}); err != nil {
+		t.Fatal(err)
+	}
+	s := <-finished
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the asynchronous confirmer retries, not the Client.
+	if got := atomic.LoadInt32(&attempts); got != 2 || s.Attempts != 2 {
+		t.Errorf("attempts = %v, Attempts = %v, want 2", got, s.Attempts)
+	}
+}
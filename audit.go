@@ -0,0 +1,312 @@
+package sns
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const maxAuditRecordSize = 16 * 1024 * 1024
+
+var ErrAuditLogTampered = errors.New("error audit log tampered")
+
+// redactedEnvelopeKeys are the envelope fields that allow confirming or
+// removing the subscription, or that carry the signature.
+var redactedEnvelopeKeys = []string{"Token", "SubscribeURL", "UnsubscribeURL", "Signature"}
+
+// AuditRecord describes a message the middleware accepted or rejected.
+type AuditRecord struct {
+	Time        time.Time
+	TopicArn    string
+	MessageType MessageType
+	MessageId   string
+	Outcome     Outcome
+	Err         string
+	// Envelope is the request body with the Token, SubscribeURL,
+	// UnsubscribeURL and Signature fields redacted. It is nil when the body
+	// could not be read.
+	Envelope []byte
+	// EnvelopeSHA256 is the hex SHA-256 of the request body as received.
+	EnvelopeSHA256 string
+}
+
+// AuditSink keeps a record of every message. When Audit fails for an accepted
+// Notification the middleware responds with 500 so that SNS retries it, and
+// counts the request as OutcomeError; with WithPool the notification is then
+// not queued. For rejected messages and subscription confirmations auditing is
+// best-effort and errors are ignored.
+type AuditSink interface {
+	Audit(ctx context.Context, rec AuditRecord) error
+}
+
+// WithAuditSink records every request to s.
+func WithAuditSink(s AuditSink) Option {
+	return func(m *Middleware) {
+		m.audit = s
+	}
+}
+
+// auditEntry is a line of the audit log. Hash is the SHA-256 of the entry
+// without Hash, and Prev is the Hash of the previous entry, so that changing,
+// inserting or removing a line breaks the chain. Removing the last lines does
+// not, which is why VerifyAuditLog returns the last hash to be compared with a
+// copy kept elsewhere.
+type auditEntry struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	TopicArn  string    `json:"topicArn"`
+	Type      string    `json:"type"`
+	MessageId string    `json:"messageId"`
+	Outcome   string    `json:"outcome"`
+	Err       string    `json:"error,omitempty"`
+	Envelope  []byte    `json:"envelope,omitempty"`
+	Digest    string    `json:"envelopeSha256,omitempty"`
+	Prev      string    `json:"prev"`
+	Hash      string    `json:"hash,omitempty"`
+}
+
+func (e auditEntry) hash() (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// follows checks that e is the entry after the one with seq and hash prev.
+func (e auditEntry) follows(seq uint64, prev string) error {
+	if e.Seq != seq+1 {
+		return fmt.Errorf("seq %d", e.Seq)
+	}
+	if e.Prev != prev {
+		return errors.New("previous hash mismatch")
+	}
+	h, err := e.hash()
+	if err != nil {
+		return err
+	}
+	if h != e.Hash {
+		return errors.New("hash mismatch")
+	}
+	return nil
+}
+
+// FileAuditSink appends hash-chained JSON lines to a file and fsyncs each of
+// them. VerifyAuditLog, or the snsaudit command, checks the chain.
+type FileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+	seq  uint64
+	prev string
+	size int64
+	torn bool
+}
+
+// OpenFileAuditSink opens the audit log at path, continuing its chain. The
+// existing chain is verified first, so that a tampered log is not extended. A
+// partially written last line, left by a crash, is removed.
+func OpenFileAuditSink(path string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileAuditSink{file: f}
+	var (
+		size int64
+		line int
+	)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), maxAuditRecordSize)
+	sc.Split(scanLines)
+	for sc.Scan() {
+		line++
+		b := sc.Bytes()
+		if !bytes.HasSuffix(b, []byte("\n")) {
+			break
+		}
+		var e auditEntry
+		if err := json.Unmarshal(b, &e); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%w: line %d: %v", ErrAuditLogTampered, line, err)
+		}
+		if err := e.follows(s.seq, s.prev); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%w: line %d: %v", ErrAuditLogTampered, line, err)
+		}
+		s.seq = e.Seq
+		s.prev = e.Hash
+		size += int64(len(b))
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.size = size
+	return s, nil
+}
+
+func (s *FileAuditSink) Audit(ctx context.Context, rec AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := auditEntry{
+		Seq:       s.seq + 1,
+		Time:      rec.Time.UTC(),
+		TopicArn:  rec.TopicArn,
+		Type:      rec.MessageType.String(),
+		MessageId: rec.MessageId,
+		Outcome:   string(rec.Outcome),
+		Err:       rec.Err,
+		Envelope:  rec.Envelope,
+		Digest:    rec.EnvelopeSHA256,
+		Prev:      s.prev,
+	}
+	h, err := e.hash()
+	if err != nil {
+		return err
+	}
+	e.Hash = h
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if s.torn {
+		if err := s.rewind(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(append(b, '\n'))
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		if n > 0 {
+			s.rewind()
+		}
+		return err
+	}
+	s.size += int64(n)
+	s.seq = e.Seq
+	s.prev = e.Hash
+	return nil
+}
+
+// rewind removes a partially written record after a failed write, so that the
+// next record continues the chain. Until it succeeds, Audit fails.
+func (s *FileAuditSink) rewind() error {
+	if err := s.file.Truncate(s.size); err != nil {
+		s.torn = true
+		return err
+	}
+	if _, err := s.file.Seek(s.size, io.SeekStart); err != nil {
+		s.torn = true
+		return err
+	}
+	s.torn = false
+	return nil
+}
+
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// VerifyAuditLog checks the hash chain of an audit log written by
+// FileAuditSink and returns the number of records and the hash of the last
+// one. The error wraps ErrAuditLogTampered and names the first line that does
+// not match. A partially written last line is ignored, as OpenFileAuditSink
+// removes it.
+func VerifyAuditLog(r io.Reader) (int, string, error) {
+	var (
+		n    int
+		prev string
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxAuditRecordSize)
+	sc.Split(scanLines)
+	for sc.Scan() {
+		if !bytes.HasSuffix(sc.Bytes(), []byte("\n")) {
+			break
+		}
+		n++
+		var e auditEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return n - 1, prev, fmt.Errorf("%w: line %d: %v", ErrAuditLogTampered, n, err)
+		}
+		if err := e.follows(uint64(n-1), prev); err != nil {
+			return n - 1, prev, fmt.Errorf("%w: line %d: %v", ErrAuditLogTampered, n, err)
+		}
+		prev = e.Hash
+	}
+	if err := sc.Err(); err != nil {
+		return n, prev, err
+	}
+	return n, prev, nil
+}
+
+// redactEnvelope returns body with the values of redactedEnvelopeKeys
+// replaced. A body that is not a JSON object only has secrets replaced.
+func redactEnvelope(body []byte, secrets ...string) []byte {
+	if body == nil {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return []byte(redact(string(body), secrets...))
+	}
+	for _, key := range redactedEnvelopeKeys {
+		if _, ok := fields[key]; ok {
+			fields[key] = json.RawMessage(`"` + redacted + `"`)
+		}
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return []byte(redact(string(body), secrets...))
+	}
+	return b
+}
+
+func envelopeDigest(body []byte) string {
+	if body == nil {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// scanLines is bufio.ScanLines keeping the line terminator, so that a last
+// line without one can be told apart.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,336 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddleware_Subscribe_AuditSink(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	body, _ := json.Marshal(Notification{
+		Type:           "Notification",
+		MessageId:      "1",
+		TopicArn:       topicARN,
+		Message:        "Hello world!",
+		Signature:      "c2lnbmF0dXJl",
+		UnsubscribeURL: "https://sns.us-west-2.amazonaws.com/?Action=Unsubscribe&SubscriptionArn=arn:aws:sns:us-west-2:123456789012:MyTopic:1",
+	})
+	sum := sha256.Sum256(body)
+
+	tests := map[string]struct {
+		signatureErr   error
+		auditErr       error
+		pool           bool
+		wantOutcome    Outcome
+		wantLogged     Outcome
+		wantCalled     bool
+		wantStatusCode int
+	}{
+		"ok": {
+			wantOutcome:    OutcomeOK,
+			wantLogged:     OutcomeOK,
+			wantCalled:     true,
+			wantStatusCode: http.StatusOK,
+		},
+		"bad-signature": {
+			signatureErr:   ErrInvalidSignature,
+			wantOutcome:    OutcomeBadSignature,
+			wantLogged:     OutcomeBadSignature,
+			wantStatusCode: http.StatusForbidden,
+		},
+		"audit failed": {
+			auditErr:       errors.New("disk full"),
+			wantOutcome:    OutcomeOK,
+			wantLogged:     OutcomeError,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"ok with pool": {
+			pool:           true,
+			wantOutcome:    OutcomeOK,
+			wantLogged:     OutcomeOK,
+			wantCalled:     true,
+			wantStatusCode: http.StatusOK,
+		},
+		"audit failed with pool": {
+			auditErr:       errors.New("disk full"),
+			pool:           true,
+			wantOutcome:    OutcomeOK,
+			wantLogged:     OutcomeError,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				records []AuditRecord
+				logged  []Outcome
+				called  bool
+			)
+			var pool *Pool
+			opts := []Option{}
+			if tt.pool {
+				pool = NewPool(NotificationHandlerFunc(func(ctx context.Context, msg Notification) error {
+					called = true
+					return nil
+				}))
+				opts = append(opts, WithPool(pool))
+			}
+			m := NewMiddleware(append(opts,
+				WithAuditSink(auditSinkFunc(func(ctx context.Context, rec AuditRecord) error {
+					records = append(records, rec)
+					return tt.auditErr
+				})),
+				WithLogger(LoggerFunc(func(ctx context.Context, rec LogRecord) {
+					logged = append(logged, rec.Outcome)
+				})),
+			)...)
+			setSubscriber(m, &mockSubscriber{
+				ExpectValidateCertURL: func(certURL string) error {
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return tt.signatureErr
+				},
+			})
+
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, "Notification")
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}).ServeHTTP(w, req)
+			if pool != nil {
+				if err := pool.Shutdown(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("Subscribe() = %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("called = %v, want %v", called, tt.wantCalled)
+			}
+			if len(records) != 1 {
+				t.Fatalf("records = %v, want 1", records)
+			}
+			if len(logged) != 1 || logged[0] != tt.wantLogged {
+				t.Errorf("logged = %v, want [%v]", logged, tt.wantLogged)
+			}
+			rec := records[0]
+			if rec.Outcome != tt.wantOutcome || rec.MessageId != "1" || rec.TopicArn != topicARN || rec.MessageType != MessageTypeNotification {
+				t.Errorf("AuditRecord = %+v", rec)
+			}
+			var envelope Notification
+			if err := json.Unmarshal(rec.Envelope, &envelope); err != nil {
+				t.Fatal(err)
+			}
+			if envelope.Message != "Hello world!" || envelope.Signature != redacted || envelope.UnsubscribeURL != redacted {
+				t.Errorf("Envelope = %s", rec.Envelope)
+			}
+			if rec.EnvelopeSHA256 != hex.EncodeToString(sum[:]) {
+				t.Errorf("EnvelopeSHA256 = %v, want %x", rec.EnvelopeSHA256, sum)
+			}
+			if rec.Time.IsZero() {
+				t.Errorf("Time should be set")
+			}
+		})
+	}
+}
+
+type auditSinkFunc func(ctx context.Context, rec AuditRecord) error
+
+func (f auditSinkFunc) Audit(ctx context.Context, rec AuditRecord) error {
+	return f(ctx, rec)
+}
+
+func TestFileAuditSink(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	ctx := context.Background()
+	write := func(outcomes ...Outcome) {
+		t.Helper()
+
+		s, err := OpenFileAuditSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		for _, o := range outcomes {
+			rec := AuditRecord{
+				Time:        time.Now(),
+				TopicArn:    "arn:aws:sns:us-west-2:123456789012:MyTopic",
+				MessageType: MessageTypeNotification,
+				MessageId:   "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+				Outcome:     o,
+				Envelope:    []byte(`{"Type":"Notification"}`),
+			}
+			if err := s.Audit(ctx, rec); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	write(OutcomeOK, OutcomeBadSignature)
+	// A crash while appending leaves a partial line, which is removed on open.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":3,"time":`)
+	f.Close()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _, err := VerifyAuditLog(bytes.NewReader(b)); err != nil || n != 2 {
+		t.Fatalf("VerifyAuditLog() = %v, %v, want 2 records", n, err)
+	}
+	write(OutcomeOK)
+
+	b, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, last, err := VerifyAuditLog(bytes.NewReader(b))
+	if err != nil || n != 3 || len(last) != 64 {
+		t.Fatalf("VerifyAuditLog() = %v, %q, %v, want 3 records", n, last, err)
+	}
+
+	lines := strings.SplitAfter(string(b), "\n")
+	tests := map[string]struct {
+		log      string
+		wantN    int
+		wantLine string
+	}{
+		"changed": {
+			log:      lines[0] + strings.Replace(lines[1], `"bad-signature"`, `"ok"`, 1) + lines[2],
+			wantN:    1,
+			wantLine: "line 2",
+		},
+		"removed": {
+			log:      lines[0] + lines[2],
+			wantN:    1,
+			wantLine: "line 2",
+		},
+		"reordered": {
+			log:      lines[1] + lines[0] + lines[2],
+			wantN:    0,
+			wantLine: "line 1",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			n, _, err := VerifyAuditLog(strings.NewReader(tt.log))
+			if !errors.Is(err, ErrAuditLogTampered) || !strings.Contains(err.Error(), tt.wantLine) {
+				t.Errorf("VerifyAuditLog() error = %v, want %v at %s", err, ErrAuditLogTampered, tt.wantLine)
+			}
+			if n != tt.wantN {
+				t.Errorf("VerifyAuditLog() = %v, want %v", n, tt.wantN)
+			}
+
+			path := filepath.Join(t.TempDir(), "audit.log")
+			if err := os.WriteFile(path, []byte(tt.log), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := OpenFileAuditSink(path); !errors.Is(err, ErrAuditLogTampered) || !strings.Contains(err.Error(), tt.wantLine) {
+				t.Errorf("OpenFileAuditSink() error = %v, want %v at %s", err, ErrAuditLogTampered, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestFileAuditSink_TornWrite(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	s, err := OpenFileAuditSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	rec := AuditRecord{
+		Time:        time.Now(),
+		TopicArn:    "arn:aws:sns:us-west-2:123456789012:MyTopic",
+		MessageType: MessageTypeNotification,
+		MessageId:   "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		Outcome:     OutcomeOK,
+	}
+	if err := s.Audit(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	// A failed write left a partial record that could not be truncated.
+	s.mu.Lock()
+	if _, err := s.file.WriteString(`{"seq":2,"time":`); err != nil {
+		t.Fatal(err)
+	}
+	s.torn = true
+	s.mu.Unlock()
+	if err := s.Audit(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _, err := VerifyAuditLog(bytes.NewReader(b)); err != nil || n != 2 {
+		t.Errorf("VerifyAuditLog() = %v, %v, want 2 records", n, err)
+	}
+}
+
+func TestRedactEnvelope(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		body    string
+		secrets []string
+		want    string
+	}{
+		{
+			name: "SubscriptionConfirmation",
+			body: `{"Type":"SubscriptionConfirmation","Token":"token","SubscribeURL":"https://sns.us-west-2.amazonaws.com/?Token=token","Signature":"sig"}`,
+			want: `{"Signature":"[REDACTED]","SubscribeURL":"[REDACTED]","Token":"[REDACTED]","Type":"SubscriptionConfirmation"}`,
+		},
+		{
+			name:    "not JSON",
+			body:    `Token=token`,
+			secrets: []string{"token"},
+			want:    `Token=[REDACTED]`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := string(redactEnvelope([]byte(tt.body), tt.secrets...)); got != tt.want {
+				t.Errorf("redactEnvelope() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
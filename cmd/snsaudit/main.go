@@ -0,0 +1,42 @@
+// Command snsaudit verifies the hash chain of audit logs written by
+// sns.FileAuditSink.
+//
+//	snsaudit verify audit.log
+//
+// It prints the number of records and the hash of the last one, which should
+// be compared with a copy kept elsewhere to detect removal of the last lines.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 2 || args[0] != "verify" {
+		fmt.Fprintln(stderr, "usage: snsaudit verify <audit.log>")
+		return 2
+	}
+
+	f, err := os.Open(args[1])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	n, last, err := sns.VerifyAuditLog(f)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v (%d records verified)\n", args[1], err, n)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s: %d records verified, last hash %s\n", args[1], n, last)
+	return 0
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	s, err := sns.OpenFileAuditSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Audit(context.Background(), sns.AuditRecord{Time: time.Now(), Outcome: sns.OutcomeOK}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := filepath.Join(dir, "tampered.log")
+	if err := os.WriteFile(tampered, bytes.Replace(b, []byte(`"ok"`), []byte(`"denied"`), 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "it verifies the log",
+			args:       []string{"verify", path},
+			wantCode:   0,
+			wantStdout: "2 records verified",
+		},
+		{
+			name:       "it reports the tampered line",
+			args:       []string{"verify", tampered},
+			wantCode:   1,
+			wantStderr: "line 1",
+		},
+		{
+			name:       "it prints usage",
+			args:       []string{path},
+			wantCode:   2,
+			wantStderr: "usage",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			if code := run(tt.args, stdout, stderr); code != tt.wantCode {
+				t.Errorf("run() = %v, want %v", code, tt.wantCode)
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package sns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SubscriptionConfirmationTTL is how long the Token of a SubscriptionConfirmation is valid.
+const SubscriptionConfirmationTTL = 3 * 24 * time.Hour
+
+var (
+	ErrNotFoundPendingConfirmation = errors.New("not found pending confirmation")
+	ErrConfirmationExpired         = errors.New("error confirmation expired")
+	ErrInvalidDecision             = errors.New("error invalid decision")
+)
+
+type Decision int
+
+const (
+	DecisionConfirm Decision = iota + 1
+	DecisionDeny
+	DecisionDefer
+)
+
+// SubscriptionConfirmationPolicy decides what the middleware does with a
+// verified SubscriptionConfirmation. Deferred confirmations are kept in the
+// PendingStore until Middleware.ConfirmPending or Middleware.DenyPending is called.
+// Any other Decision is rejected with 500 and the subscription is not confirmed.
+type SubscriptionConfirmationPolicy func(ctx context.Context, msg SubscriptionConfirmation) (Decision, error)
+
+// WithOnSubscriptionConfirmation sets the policy applied to subscription
+// confirmations. Without it every confirmation for the topic is confirmed.
+func WithOnSubscriptionConfirmation(policy SubscriptionConfirmationPolicy) Option {
+	return func(m *Middleware) {
+		m.onSubscriptionConfirmation = policy
+	}
+}
+
+// WithPendingStore sets where deferred confirmations are kept.
+func WithPendingStore(s PendingStore) Option {
+	return func(m *Middleware) {
+		m.pending = s
+	}
+}
+
+// Pending returns the deferred confirmations.
+func (m *Middleware) Pending(ctx context.Context) ([]SubscriptionConfirmation, error) {
+	return m.pending.List(ctx)
+}
+
+// ConfirmPending confirms the deferred confirmation with token.
+func (m *Middleware) ConfirmPending(ctx context.Context, token string) error {
+	msg, err := m.pending.Get(ctx, token)
+	if err != nil {
+		return err
+	}
+	if expired(msg, time.Now()) {
+		if err := m.pending.Delete(ctx, token); err != nil {
+			return err
+		}
+		return ErrConfirmationExpired
+	}
+	if _, err := m.confirm(ctx, msg); err != nil {
+		return err
+	}
+	return m.pending.Delete(ctx, token)
+}
+
+// DenyPending discards the deferred confirmation with token.
+func (m *Middleware) DenyPending(ctx context.Context, token string) error {
+	if _, err := m.pending.Get(ctx, token); err != nil {
+		return err
+	}
+	return m.pending.Delete(ctx, token)
+}
+
+func expired(msg SubscriptionConfirmation, now time.Time) bool {
+	if msg.Timestamp.IsZero() {
+		return true
+	}
+	return now.After(msg.Timestamp.Time().Add(SubscriptionConfirmationTTL))
+}
+
+// PendingStore keeps deferred subscription confirmations keyed by Token.
+type PendingStore interface {
+	Save(ctx context.Context, msg SubscriptionConfirmation) error
+	Get(ctx context.Context, token string) (SubscriptionConfirmation, error)
+	Delete(ctx context.Context, token string) error
+	List(ctx context.Context) ([]SubscriptionConfirmation, error)
+}
+
+type MemoryPendingStore struct {
+	mu      sync.Mutex
+	pending map[string]SubscriptionConfirmation
+}
+
+func NewMemoryPendingStore() *MemoryPendingStore {
+	return &MemoryPendingStore{
+		pending: map[string]SubscriptionConfirmation{},
+	}
+}
+
+func (s *MemoryPendingStore) Save(ctx context.Context, msg SubscriptionConfirmation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending[msg.Token] = msg
+	return nil
+}
+
+func (s *MemoryPendingStore) Get(ctx context.Context, token string) (SubscriptionConfirmation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.pending[token]
+	if !ok {
+		return SubscriptionConfirmation{}, ErrNotFoundPendingConfirmation
+	}
+	return msg, nil
+}
+
+func (s *MemoryPendingStore) Delete(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pending, token)
+	return nil
+}
+
+func (s *MemoryPendingStore) List(ctx context.Context) ([]SubscriptionConfirmation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedPending(s.pending), nil
+}
+
+// FilePendingStore persists deferred confirmations as a JSON file so they
+// survive restarts.
+type FilePendingStore struct {
+	path string
+
+	mu      sync.Mutex
+	pending map[string]SubscriptionConfirmation
+}
+
+func NewFilePendingStore(path string) (*FilePendingStore, error) {
+	s := &FilePendingStore{
+		path:    path,
+		pending: map[string]SubscriptionConfirmation{},
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []SubscriptionConfirmation
+	if err := json.Unmarshal(b, &msgs); err != nil {
+		return nil, err
+	}
+	for _, msg := range msgs {
+		s.pending[msg.Token] = msg
+	}
+	return s, nil
+}
+
+func (s *FilePendingStore) Save(ctx context.Context, msg SubscriptionConfirmation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending[msg.Token] = msg
+	return s.write()
+}
+
+func (s *FilePendingStore) Get(ctx context.Context, token string) (SubscriptionConfirmation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.pending[token]
+	if !ok {
+		return SubscriptionConfirmation{}, ErrNotFoundPendingConfirmation
+	}
+	return msg, nil
+}
+
+func (s *FilePendingStore) Delete(ctx context.Context, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pending, token)
+	return s.write()
+}
+
+func (s *FilePendingStore) List(ctx context.Context) ([]SubscriptionConfirmation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedPending(s.pending), nil
+}
+
+// write replaces the file atomically so a crash never leaves a partial list.
+func (s *FilePendingStore) write() error {
+	b, err := json.Marshal(sortedPending(s.pending))
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+func sortedPending(pending map[string]SubscriptionConfirmation) []SubscriptionConfirmation {
+	msgs := make([]SubscriptionConfirmation, 0, len(pending))
+	for _, msg := range pending {
+		msgs = append(msgs, msg)
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		return msgs[i].Timestamp.Time().Before(msgs[j].Timestamp.Time())
+	})
+	return msgs
+}
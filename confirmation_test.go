@@ -0,0 +1,229 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMiddleware_Subscribe_OnSubscriptionConfirmation(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	msg := SubscriptionConfirmation{
+		Type:      "SubscriptionConfirmation",
+		MessageId: "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+		Token:     "Ethevee8dae4mie3",
+		TopicArn:  topicARN,
+		Timestamp: mustParseTimestamp("2012-04-26T20:45:04.751Z"),
+	}
+
+	tests := map[string]struct {
+		decision       Decision
+		err            error
+		wantConfirmed  bool
+		wantPending    []SubscriptionConfirmation
+		wantStatusCode int
+	}{
+		"confirm": {
+			decision:       DecisionConfirm,
+			wantConfirmed:  true,
+			wantPending:    []SubscriptionConfirmation{},
+			wantStatusCode: http.StatusOK,
+		},
+		"deny": {
+			decision:       DecisionDeny,
+			wantConfirmed:  false,
+			wantPending:    []SubscriptionConfirmation{},
+			wantStatusCode: http.StatusForbidden,
+		},
+		"defer": {
+			decision:       DecisionDefer,
+			wantConfirmed:  false,
+			wantPending:    []SubscriptionConfirmation{msg},
+			wantStatusCode: http.StatusOK,
+		},
+		"error": {
+			err:            errors.New("failed"),
+			wantConfirmed:  false,
+			wantPending:    []SubscriptionConfirmation{},
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"invalid decision": {
+			decision:       Decision(0),
+			wantConfirmed:  false,
+			wantPending:    []SubscriptionConfirmation{},
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		"unknown decision": {
+			decision:       DecisionDefer + 1,
+			wantConfirmed:  false,
+			wantPending:    []SubscriptionConfirmation{},
+			wantStatusCode: http.StatusInternalServerError,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			confirmed := false
+			m := NewMiddleware(WithOnSubscriptionConfirmation(func(ctx context.Context, got SubscriptionConfirmation) (Decision, error) {
+				if got != msg {
+					t.Errorf("msg = %v, want %v", got, msg)
+				}
+				return tt.decision, tt.err
+			}))
+			setSubscriber(m, &mockSubscriber{
+				ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+					confirmed = true
+					return &ConfirmSubscriptionResult{}, nil
+				},
+				ExpectValidateCertURL: func(certURL string) error {
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return nil
+				},
+			})
+
+			b, _ := json.Marshal(msg)
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, "SubscriptionConfirmation")
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("Subscribe() = %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if confirmed != tt.wantConfirmed {
+				t.Errorf("confirmed = %v, want %v", confirmed, tt.wantConfirmed)
+			}
+			pending, err := m.Pending(context.Background())
+			if err != nil {
+				t.Errorf("err should be nil, but got %q", err)
+			}
+			if !reflect.DeepEqual(pending, tt.wantPending) {
+				t.Errorf("Pending() = %v, want %v", pending, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestMiddleware_ConfirmPending(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		timestamp     Timestamp
+		token         string
+		wantConfirmed bool
+		want          error
+	}{
+		"success": {
+			timestamp:     NewTimestamp(time.Now().Add(-time.Hour)),
+			token:         "Ethevee8dae4mie3",
+			wantConfirmed: true,
+			want:          nil,
+		},
+		"expired": {
+			timestamp:     NewTimestamp(time.Now().Add(-SubscriptionConfirmationTTL - time.Minute)),
+			token:         "Ethevee8dae4mie3",
+			wantConfirmed: false,
+			want:          ErrConfirmationExpired,
+		},
+		"not found": {
+			timestamp:     NewTimestamp(time.Now()),
+			token:         "unknown",
+			wantConfirmed: false,
+			want:          ErrNotFoundPendingConfirmation,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			confirmed := false
+			m := NewMiddleware()
+			setSubscriber(m, &mockSubscriber{
+				ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+					confirmed = true
+					return &ConfirmSubscriptionResult{}, nil
+				},
+			})
+
+			ctx := context.Background()
+			msg := SubscriptionConfirmation{Token: "Ethevee8dae4mie3", Timestamp: tt.timestamp}
+			if err := m.pending.Save(ctx, msg); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := m.ConfirmPending(ctx, tt.token); err != tt.want {
+				t.Errorf("ConfirmPending() = %v, want %v", err, tt.want)
+			}
+			if confirmed != tt.wantConfirmed {
+				t.Errorf("confirmed = %v, want %v", confirmed, tt.wantConfirmed)
+			}
+			if tt.token == msg.Token {
+				if _, err := m.pending.Get(ctx, msg.Token); err != ErrNotFoundPendingConfirmation {
+					t.Errorf("Get() = %v, want %v", err, ErrNotFoundPendingConfirmation)
+				}
+			}
+		})
+	}
+}
+
+func TestFilePendingStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "pending.json")
+	msgs := []SubscriptionConfirmation{
+		{Token: "token-1", TopicArn: "arn:aws:sns:us-west-2:123456789012:MyTopic", Timestamp: mustParseTimestamp("2012-04-26T20:45:04.751Z")},
+		{Token: "token-2", TopicArn: "arn:aws:sns:us-west-2:123456789012:MyTopic", Timestamp: mustParseTimestamp("2012-04-27T20:45:04.751Z")},
+	}
+
+	s, err := NewFilePendingStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range msgs {
+		if err := s.Save(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err = NewFilePendingStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, msgs) {
+		t.Errorf("List() = %v, want %v", got, msgs)
+	}
+
+	if err := s.Delete(ctx, "token-1"); err != nil {
+		t.Fatal(err)
+	}
+	s, err = NewFilePendingStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "token-1"); err != ErrNotFoundPendingConfirmation {
+		t.Errorf("Get() = %v, want %v", err, ErrNotFoundPendingConfirmation)
+	}
+	if got, err := s.Get(ctx, "token-2"); err != nil || got != msgs[1] {
+		t.Errorf("Get() = %v, %v, want %v", got, err, msgs[1])
+	}
+}
@@ -4,23 +4,99 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 )
 
+type contextKey int
+
 const (
-	ContextKeyNotification string = "sns.notification"
+	notificationKey contextKey = iota
+	rawEnvelopeKey
+	verificationInfoKey
 )
 
 var (
 	ErrNotFoundNotification = errors.New("not found Notification")
+	ErrNotFoundRawEnvelope  = errors.New("not found raw envelope")
+
+	ErrNotFoundVerificationInfo = errors.New("not found VerificationInfo")
 )
 
+// VerificationInfo describes how a Notification was verified by the middleware.
+type VerificationInfo struct {
+	TopicArn         string
+	SubscriptionArn  string
+	MessageId        string
+	SigningCertURL   string
+	SignatureVersion string
+	VerifiedAt       time.Time
+}
+
+// NewContext returns a copy of ctx that carries msg.
+func NewContext(ctx context.Context, msg Notification) context.Context {
+	return context.WithValue(ctx, notificationKey, msg)
+}
+
+// FromContext returns the Notification stored in ctx, if any.
+func FromContext(ctx context.Context) (Notification, bool) {
+	msg, ok := ctx.Value(notificationKey).(Notification)
+	return msg, ok
+}
+
+// NewRawEnvelopeContext returns a copy of ctx that carries the raw envelope.
+func NewRawEnvelopeContext(ctx context.Context, body []byte) context.Context {
+	return context.WithValue(ctx, rawEnvelopeKey, body)
+}
+
+// RawEnvelopeFromContext returns the raw envelope stored in ctx, if any.
+func RawEnvelopeFromContext(ctx context.Context) ([]byte, bool) {
+	body, ok := ctx.Value(rawEnvelopeKey).([]byte)
+	return body, ok
+}
+
+// NewVerificationInfoContext returns a copy of ctx that carries info.
+func NewVerificationInfoContext(ctx context.Context, info VerificationInfo) context.Context {
+	return context.WithValue(ctx, verificationInfoKey, info)
+}
+
+// VerificationInfoFromContext returns the VerificationInfo stored in ctx, if any.
+func VerificationInfoFromContext(ctx context.Context) (VerificationInfo, bool) {
+	info, ok := ctx.Value(verificationInfoKey).(VerificationInfo)
+	return info, ok
+}
+
 func SetNotification(r *http.Request, msg Notification) context.Context {
-	return context.WithValue(r.Context(), ContextKeyNotification, msg)
+	return NewContext(r.Context(), msg)
 }
 
 func GetNotification(r *http.Request) (Notification, error) {
-	if msg, ok := r.Context().Value(ContextKeyNotification).(Notification); ok {
+	if msg, ok := FromContext(r.Context()); ok {
 		return msg, nil
 	}
 	return Notification{}, ErrNotFoundNotification
 }
+
+// SetRawEnvelope stores the verified request body as received from SNS.
+func SetRawEnvelope(r *http.Request, body []byte) context.Context {
+	return NewRawEnvelopeContext(r.Context(), body)
+}
+
+// GetRawEnvelope returns the verified request body as received from SNS.
+// The returned slice must not be modified.
+func GetRawEnvelope(r *http.Request) ([]byte, error) {
+	if body, ok := RawEnvelopeFromContext(r.Context()); ok {
+		return body, nil
+	}
+	return nil, ErrNotFoundRawEnvelope
+}
+
+func SetVerificationInfo(r *http.Request, info VerificationInfo) context.Context {
+	return NewVerificationInfoContext(r.Context(), info)
+}
+
+func GetVerificationInfo(r *http.Request) (VerificationInfo, error) {
+	if info, ok := VerificationInfoFromContext(r.Context()); ok {
+		return info, nil
+	}
+	return VerificationInfo{}, ErrNotFoundVerificationInfo
+}
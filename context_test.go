@@ -1,9 +1,11 @@
 package sns
 
 import (
+	"context"
 	"net/http"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestGetNotification(t *testing.T) {
@@ -20,7 +22,7 @@ func TestGetNotification(t *testing.T) {
 				MessageId:        "2e41209f-2772-4a8d-8014-ed1fc296499d",
 				TopicArn:         "arn:aws:sns:ap-northeast-1:000000000000:en-topic",
 				Message:          "test",
-				Timestamp:        "2021-12-17T02:28:11.491Z",
+				Timestamp:        mustParseTimestamp("2021-12-17T02:28:11.491Z"),
 				SignatureVersion: "1",
 				Signature:        "EXAMPLEpH+DcEwjAPg8O9mY8dReBSwksfg2S7WKQcikcNKWLQjwu6A4VbeS0QHVCkhRS7fUQvi2egU3N858fiTDN6bkkOxYDVrY0Ad8L10Hs3zH81mtnPk5uvvolIC1CXGu43obcgFxeL3khZl8IKvO61GWB6jI9b5+gLPoBc1Q=",
 				SigningCertURL:   "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-0000000000000000000000.pem",
@@ -46,3 +48,138 @@ func TestGetNotification(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRawEnvelope(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		body []byte
+		want []byte
+		err  error
+	}{
+		{
+			name: "success",
+			body: []byte(`{"Type":"Notification","Message":"test"}`),
+			want: []byte(`{"Type":"Notification","Message":"test"}`),
+			err:  nil,
+		},
+		{
+			name: "not found",
+			body: nil,
+			want: nil,
+			err:  ErrNotFoundRawEnvelope,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &http.Request{}
+			if tt.body != nil {
+				r = r.WithContext(SetRawEnvelope(r, tt.body))
+			}
+
+			got, err := GetRawEnvelope(r)
+			if err != tt.err {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRawEnvelope() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetVerificationInfo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		want VerificationInfo
+	}{
+		{
+			name: "success",
+			want: VerificationInfo{
+				TopicArn:         "arn:aws:sns:ap-northeast-1:000000000000:en-topic",
+				SubscriptionArn:  "arn:aws:sns:ap-northeast-1:000000000000:en-topic:2bcfbf39-05c3-41de-beaa-fcfcc21c8f55",
+				MessageId:        "2e41209f-2772-4a8d-8014-ed1fc296499d",
+				SigningCertURL:   "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-0000000000000000000000.pem",
+				SignatureVersion: "1",
+				VerifiedAt:       time.Date(2021, 12, 17, 2, 28, 11, 0, time.UTC),
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &http.Request{}
+			r = r.WithContext(SetVerificationInfo(r, tt.want))
+
+			got, err := GetVerificationInfo(r)
+			if err != nil {
+				t.Errorf("err should be nil, but got %q", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetVerificationInfo() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		want   Notification
+		wantOK bool
+	}{
+		{
+			name: "success",
+			ctx: NewContext(context.Background(), Notification{
+				Type:      "Notification",
+				MessageId: "2e41209f-2772-4a8d-8014-ed1fc296499d",
+				TopicArn:  "arn:aws:sns:ap-northeast-1:000000000000:en-topic",
+				Message:   "test",
+			}),
+			want: Notification{
+				Type:      "Notification",
+				MessageId: "2e41209f-2772-4a8d-8014-ed1fc296499d",
+				TopicArn:  "arn:aws:sns:ap-northeast-1:000000000000:en-topic",
+				Message:   "test",
+			},
+			wantOK: true,
+		},
+		{
+			name:   "not found",
+			ctx:    context.Background(),
+			want:   Notification{},
+			wantOK: false,
+		},
+		{
+			name:   "string key does not collide",
+			ctx:    context.WithValue(context.Background(), "sns.notification", Notification{Message: "test"}), //nolint:staticcheck
+			want:   Notification{},
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := FromContext(tt.ctx)
+			if ok != tt.wantOK {
+				t.Errorf("FromContext() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromContext() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,460 @@
+package sns
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	walOpAppend = "append"
+	walOpDone   = "done"
+
+	maxWALRecordSize = 16 * 1024 * 1024
+)
+
+var ErrDurableQueueLocked = errors.New("error durable queue locked by another process")
+
+// DurableQueue is a NotificationHandler that appends each notification to a
+// write-ahead log on local disk and fsyncs it before Dispatch acknowledges the
+// delivery. Workers pass the logged notifications to the handler and mark them
+// done; notifications that were not done when the process stopped are
+// delivered again by OpenDurableQueue, so handlers must be idempotent. A
+// notification whose handler keeps failing is retried with the retry policy and
+// then left in the log until it is opened again.
+type DurableQueue struct {
+	path       string
+	handler    NotificationHandler
+	workers    int
+	maxPending int
+	retry      RetryPolicy
+	onComplete []func(ctx context.Context, msg Notification, err error)
+
+	wg   sync.WaitGroup
+	stop chan struct{}
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	lock     *os.File
+	file     *os.File
+	size     int64
+	torn     bool
+	seq      uint64
+	pending  []walRecord
+	inflight int
+	failed   []walRecord
+	closed   bool
+}
+
+// walRecord is a line of the write-ahead log.
+type walRecord struct {
+	Op               string            `json:"op"`
+	Seq              uint64            `json:"seq"`
+	Notification     *Notification     `json:"notification,omitempty"`
+	RawEnvelope      []byte            `json:"rawEnvelope,omitempty"`
+	VerificationInfo *VerificationInfo `json:"verificationInfo,omitempty"`
+}
+
+type DurableQueueOption func(*DurableQueue)
+
+// WithDurableQueueWorkers sets the number of workers.
+func WithDurableQueueWorkers(n int) DurableQueueOption {
+	return func(q *DurableQueue) {
+		q.workers = n
+	}
+}
+
+// WithDurableQueueMaxPending limits the notifications that are logged but not
+// done, not counting the ones the handler failed. HandleNotification returns
+// ErrQueueFull above it. Zero means no limit.
+func WithDurableQueueMaxPending(n int) DurableQueueOption {
+	return func(q *DurableQueue) {
+		q.maxPending = n
+	}
+}
+
+// WithDurableQueueRetryPolicy sets how a notification is retried when the
+// handler returns an error. DefaultRetryPolicy is used unless set.
+func WithDurableQueueRetryPolicy(p RetryPolicy) DurableQueueOption {
+	return func(q *DurableQueue) {
+		q.retry = p
+	}
+}
+
+// WithOnDurableQueueComplete is called after each notification with the error
+// returned by the last attempt of the handler. The notification is marked done
+// only when it is nil.
+func WithOnDurableQueueComplete(f func(ctx context.Context, msg Notification, err error)) DurableQueueOption {
+	return func(q *DurableQueue) {
+		q.onComplete = append(q.onComplete, f)
+	}
+}
+
+// OpenDurableQueue opens the log at path, queues the notifications that were
+// not done and starts the workers handling them with h. The log is locked until
+// Shutdown; opening it again returns ErrDurableQueueLocked. On platforms
+// without flock the log is not locked and must be opened by one process only.
+func OpenDurableQueue(path string, h NotificationHandler, opts ...DurableQueueOption) (*DurableQueue, error) {
+	q := &DurableQueue{
+		path:    path,
+		handler: h,
+		workers: DefaultPoolWorkers,
+		retry:   DefaultRetryPolicy(),
+		stop:    make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	lock, err := lockWAL(path)
+	if err != nil {
+		return nil, err
+	}
+	q.lock = lock
+	if err := q.open(); err != nil {
+		q.unlock()
+		return nil, err
+	}
+
+	q.wg.Add(q.workers)
+	for i := 0; i < q.workers; i++ {
+		go q.run()
+	}
+	return q, nil
+}
+
+// HandleNotification logs msg together with the raw envelope and
+// VerificationInfo found in ctx, and returns once the log is synced.
+func (q *DurableQueue) HandleNotification(ctx context.Context, msg Notification) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrShutdown
+	}
+	if q.maxPending > 0 && len(q.pending)+q.inflight >= q.maxPending {
+		return ErrQueueFull
+	}
+
+	rec := walRecord{
+		Op:           walOpAppend,
+		Seq:          q.seq + 1,
+		Notification: &msg,
+	}
+	if body, ok := RawEnvelopeFromContext(ctx); ok {
+		rec.RawEnvelope = body
+	}
+	if info, ok := VerificationInfoFromContext(ctx); ok {
+		rec.VerificationInfo = &info
+	}
+	if err := q.write(rec); err != nil {
+		return err
+	}
+
+	q.seq = rec.Seq
+	q.pending = append(q.pending, rec)
+	q.cond.Signal()
+	return nil
+}
+
+// Shutdown stops accepting notifications and retrying failed ones, and waits
+// until the logged ones are handled or ctx is done. Notifications left when ctx
+// is done stay in the log.
+func (q *DurableQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.stop)
+	}
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		q.file.Close()
+		q.unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *DurableQueue) run() {
+	defer q.wg.Done()
+
+	for {
+		rec, ok := q.next()
+		if !ok {
+			return
+		}
+
+		ctx := NewContext(context.Background(), *rec.Notification)
+		if rec.RawEnvelope != nil {
+			ctx = NewRawEnvelopeContext(ctx, rec.RawEnvelope)
+		}
+		if rec.VerificationInfo != nil {
+			ctx = NewVerificationInfoContext(ctx, *rec.VerificationInfo)
+		}
+		err := q.handle(ctx, *rec.Notification)
+		for _, f := range q.onComplete {
+			f(ctx, *rec.Notification, err)
+		}
+		if err != nil {
+			q.fail(rec)
+			continue
+		}
+		q.done(rec.Seq)
+	}
+}
+
+// handle calls the handler until it succeeds, the retry policy gives up or the
+// queue is shut down.
+func (q *DurableQueue) handle(ctx context.Context, msg Notification) error {
+	for attempt := 1; ; attempt++ {
+		err := q.handler.HandleNotification(ctx, msg)
+		if err == nil || attempt >= q.retry.MaxAttempts {
+			return err
+		}
+
+		delay := q.retry.backoff(attempt - 1)
+		if q.retry.OnRetry != nil {
+			q.retry.OnRetry(RetryEvent{Op: OpHandleNotification, Attempt: attempt, Delay: delay, Err: err})
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-q.stop:
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+func (q *DurableQueue) next() (walRecord, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.pending) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.pending) == 0 {
+		return walRecord{}, false
+	}
+
+	rec := q.pending[0]
+	q.pending = q.pending[1:]
+	q.inflight++
+	return rec, true
+}
+
+// fail leaves a notification the handler failed in the log, so that it is
+// delivered again when the log is opened.
+func (q *DurableQueue) fail(rec walRecord) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.inflight--
+	q.failed = append(q.failed, rec)
+	q.compact()
+}
+
+// done marks seq done.
+func (q *DurableQueue) done(seq uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.inflight--
+	if q.compact() {
+		return
+	}
+	// A lost done record only makes the notification delivered again.
+	_ = q.write(walRecord{Op: walOpDone, Seq: seq})
+}
+
+// compact replaces the log with the failed notifications once nothing else is
+// left in it, so that it does not grow without bound. It reports whether the
+// log was replaced.
+func (q *DurableQueue) compact() bool {
+	if len(q.pending) > 0 || q.inflight > 0 {
+		return false
+	}
+	if len(q.failed) == 0 {
+		if err := q.file.Truncate(0); err != nil || q.file.Sync() != nil {
+			return false
+		}
+		q.size = 0
+		q.torn = false
+		return true
+	}
+
+	f, size, err := compactWAL(q.path, q.failed)
+	if err != nil {
+		return false
+	}
+	q.file.Close()
+	q.file = f
+	q.size = size
+	q.torn = false
+	return true
+}
+
+// write appends rec to the log. When the write fails the log is truncated back
+// to the last record, or, if that fails too, the next record starts on a new
+// line so that only the partial one is lost.
+func (q *DurableQueue) write(rec walRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if q.torn {
+		b = append([]byte{'\n'}, b...)
+	}
+
+	n, err := q.file.Write(b)
+	if err == nil {
+		err = q.file.Sync()
+	}
+	if err != nil {
+		if n > 0 || q.torn {
+			q.torn = q.file.Truncate(q.size) != nil
+		}
+		return err
+	}
+	q.size += int64(n)
+	q.torn = false
+	return nil
+}
+
+// open compacts the log and opens it for appending.
+func (q *DurableQueue) open() error {
+	pending, seq, err := replayWAL(q.path)
+	if err != nil {
+		return err
+	}
+	f, size, err := compactWAL(q.path, pending)
+	if err != nil {
+		return err
+	}
+
+	q.file = f
+	q.size = size
+	q.seq = seq
+	q.pending = pending
+	return nil
+}
+
+func (q *DurableQueue) unlock() {
+	if q.lock != nil {
+		q.lock.Close()
+	}
+}
+
+// replayWAL returns the records of the log at path that are not done, and the
+// last sequence number. A partially written last line is ignored.
+func replayWAL(path string) ([]walRecord, uint64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var (
+		seq     uint64
+		order   []uint64
+		records = map[uint64]walRecord{}
+	)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), maxWALRecordSize)
+	for sc.Scan() {
+		var rec walRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			continue
+		}
+		if rec.Seq > seq {
+			seq = rec.Seq
+		}
+		switch rec.Op {
+		case walOpAppend:
+			if rec.Notification == nil {
+				continue
+			}
+			records[rec.Seq] = rec
+			order = append(order, rec.Seq)
+		case walOpDone:
+			delete(records, rec.Seq)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var pending []walRecord
+	for _, s := range order {
+		if rec, ok := records[s]; ok {
+			pending = append(pending, rec)
+		}
+	}
+	return pending, seq, nil
+}
+
+// compactWAL replaces the log at path with pending atomically, and returns it
+// opened for appending together with its size.
+func compactWAL(path string, pending []walRecord) (*os.File, int64, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return nil, 0, err
+	}
+	defer os.Remove(f.Name())
+
+	var size int64
+	w := bufio.NewWriter(f)
+	for _, rec := range pending {
+		b, err := json.Marshal(rec)
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		w.Write(b)
+		w.WriteByte('\n')
+		size += int64(len(b)) + 1
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	// The log is opened before it is renamed, so that a failure leaves the
+	// current one in use.
+	af, err := os.OpenFile(f.Name(), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		af.Close()
+		return nil, 0, err
+	}
+	return af, size, nil
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package sns
+
+import "os"
+
+// lockWAL does not lock the log on this platform, so only one process may
+// open a DurableQueue on path.
+func lockWAL(path string) (*os.File, error) {
+	return nil, nil
+}
@@ -0,0 +1,26 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package sns
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockWAL takes an exclusive lock on a file next to the log at path, which is
+// released when the returned file is closed or the process exits.
+func lockWAL(path string) (*os.File, error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrDurableQueueLocked
+		}
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,34 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package sns
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenDurableQueue_Locked(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sns.wal")
+	h := &recordingHandler{}
+	q, err := OpenDurableQueue(path, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDurableQueue(path, h); err != ErrDurableQueueLocked {
+		t.Errorf("OpenDurableQueue() error = %v, want %v", err, ErrDurableQueueLocked)
+	}
+
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	q, err = OpenDurableQueue(path, h)
+	if err != nil {
+		t.Fatalf("OpenDurableQueue() error = %v after Shutdown", err)
+	}
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
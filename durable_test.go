@@ -0,0 +1,344 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingHandler struct {
+	mu   sync.Mutex
+	msgs []Notification
+	envs [][]byte
+	done chan struct{}
+}
+
+func (h *recordingHandler) HandleNotification(ctx context.Context, msg Notification) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	body, _ := RawEnvelopeFromContext(ctx)
+	h.msgs = append(h.msgs, msg)
+	h.envs = append(h.envs, body)
+	if h.done != nil {
+		h.done <- struct{}{}
+	}
+	return nil
+}
+
+func TestDurableQueue_Crash(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sns.wal")
+	msgs := []Notification{
+		{MessageId: "1", Message: "first"},
+		{MessageId: "2", Message: "second"},
+		{MessageId: "3", Message: "third"},
+	}
+
+	// The first notification is done, the second is being handled and the
+	// third is waiting when the process crashes.
+	started := make(chan string)
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	crashed, err := OpenDurableQueue(path, NotificationHandlerFunc(func(ctx context.Context, msg Notification) error {
+		if msg.MessageId == "1" {
+			return nil
+		}
+		started <- msg.MessageId
+		<-release
+		return nil
+	}), WithDurableQueueWorkers(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range msgs {
+		ctx := NewRawEnvelopeContext(context.Background(), []byte(msg.Message))
+		if err := crashed.HandleNotification(ctx, msg); err != nil {
+			t.Fatalf("HandleNotification() error = %v", err)
+		}
+	}
+	if id := <-started; id != "2" {
+		t.Fatalf("started = %v, want 2", id)
+	}
+
+	// A crash while appending leaves a partial line.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"op":"append","seq":4,"notifica`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// The crashed process releases its files and lock.
+	crashed.mu.Lock()
+	crashed.file.Close()
+	crashed.unlock()
+	crashed.mu.Unlock()
+
+	h := &recordingHandler{done: make(chan struct{}, len(msgs))}
+	q, err := OpenDurableQueue(path, h, WithDurableQueueWorkers(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		<-h.done
+	}
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if want := msgs[1:]; !reflect.DeepEqual(h.msgs, want) {
+		t.Errorf("redelivered = %v, want %v", h.msgs, want)
+	}
+	if want := [][]byte{[]byte("second"), []byte("third")}; !reflect.DeepEqual(h.envs, want) {
+		t.Errorf("raw envelopes = %q, want %q", h.envs, want)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Errorf("log should be empty after drain, got %v, %v", fi, err)
+	}
+}
+
+func TestDurableQueue_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sns.wal")
+	release := make(chan struct{})
+	q, err := OpenDurableQueue(path, NotificationHandlerFunc(func(ctx context.Context, msg Notification) error {
+		<-release
+		return nil
+	}), WithDurableQueueWorkers(1), WithDurableQueueMaxPending(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i, want := range []error{nil, nil, ErrQueueFull} {
+		if err := q.HandleNotification(ctx, Notification{MessageId: string(rune('a' + i))}); err != want {
+			t.Errorf("HandleNotification() error = %v, want %v", err, want)
+		}
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := q.Shutdown(timeout); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := q.HandleNotification(ctx, Notification{MessageId: "z"}); err != ErrShutdown {
+		t.Errorf("HandleNotification() error = %v, want %v", err, ErrShutdown)
+	}
+
+	close(release)
+	if err := q.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+
+	h := &recordingHandler{}
+	q, err = OpenDurableQueue(path, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.msgs) != 0 {
+		t.Errorf("redelivered = %v, want none", h.msgs)
+	}
+}
+
+func TestDurableQueue_HandlerFailed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		failures      int
+		wantErr       bool
+		wantAttempts  int
+		wantRedeliver bool
+	}{
+		{
+			name:         "retried until it succeeds",
+			failures:     2,
+			wantAttempts: 3,
+		},
+		{
+			name:          "left in the log when retries are exhausted",
+			failures:      3,
+			wantErr:       true,
+			wantAttempts:  3,
+			wantRedeliver: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "sns.wal")
+			attempts := 0
+			completed := make(chan error, 1)
+			q, err := OpenDurableQueue(path, NotificationHandlerFunc(func(ctx context.Context, msg Notification) error {
+				attempts++
+				if attempts <= tt.failures {
+					return errors.New("failed")
+				}
+				return nil
+			}),
+				WithDurableQueueWorkers(1),
+				WithDurableQueueRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+				WithOnDurableQueueComplete(func(ctx context.Context, msg Notification, err error) {
+					completed <- err
+				}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			if err := q.HandleNotification(ctx, Notification{MessageId: "1"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := <-completed; (err != nil) != tt.wantErr {
+				t.Errorf("completed error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := q.Shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %v, want %v", attempts, tt.wantAttempts)
+			}
+
+			h := &recordingHandler{done: make(chan struct{}, 1)}
+			q, err = OpenDurableQueue(path, h, WithDurableQueueWorkers(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantRedeliver {
+				<-h.done
+			}
+			if err := q.Shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(h.msgs) == 1; got != tt.wantRedeliver {
+				t.Errorf("redelivered = %v, want %v", h.msgs, tt.wantRedeliver)
+			}
+		})
+	}
+}
+
+func TestDurableQueue_FailedNotCounted(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sns.wal")
+	completed := make(chan error, 1)
+	q, err := OpenDurableQueue(path, NotificationHandlerFunc(func(ctx context.Context, msg Notification) error {
+		if msg.MessageId == "1" {
+			return errors.New("failed")
+		}
+		return nil
+	}),
+		WithDurableQueueWorkers(1),
+		WithDurableQueueMaxPending(1),
+		WithDurableQueueRetryPolicy(NoRetry()),
+		WithOnDurableQueueComplete(func(ctx context.Context, msg Notification, err error) {
+			completed <- err
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := q.HandleNotification(ctx, Notification{MessageId: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	<-completed
+	// The failed notification is left in the log but does not fill the queue.
+	// The worker marks it failed right after the completion callback.
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := q.HandleNotification(ctx, Notification{MessageId: "2"})
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrQueueFull) || time.Now().After(deadline) {
+			t.Fatalf("HandleNotification() error = %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := <-completed; err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The log is compacted to the failed notification.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(b, []byte("\n")); n != 1 {
+		t.Errorf("log has %d lines, want 1:\n%s", n, b)
+	}
+	pending, _, err := replayWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Notification.MessageId != "1" {
+		t.Errorf("pending = %v, want 1", pending)
+	}
+}
+
+func TestDurableQueue_TornWrite(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sns.wal")
+	release := make(chan struct{})
+	q, err := OpenDurableQueue(path, NotificationHandlerFunc(func(ctx context.Context, msg Notification) error {
+		<-release
+		return nil
+	}), WithDurableQueueWorkers(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		close(release)
+		q.Shutdown(context.Background())
+	}()
+
+	ctx := context.Background()
+	if err := q.HandleNotification(ctx, Notification{MessageId: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	// A failed write left a partial record that could not be truncated.
+	q.mu.Lock()
+	if _, err := q.file.WriteString(`{"op":"append","seq":2,"notifica`); err != nil {
+		t.Fatal(err)
+	}
+	q.torn = true
+	q.mu.Unlock()
+	if err := q.HandleNotification(ctx, Notification{MessageId: "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, _, err := replayWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, rec := range pending {
+		ids = append(ids, rec.Notification.MessageId)
+	}
+	if want := []string{"1", "2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("pending = %v, want %v", ids, want)
+	}
+}
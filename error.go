@@ -10,4 +10,26 @@ var (
 	ErrInvalidCertBody         = errors.New("error invalid cert body")
 	ErrInvalidSignatureVersion = errors.New("error invalid signature version")
 	ErrInvalidSignature        = errors.New("error invalid signature")
+	ErrRequestBodyTooLarge     = errors.New("error request body too large")
+	ErrTrailingData            = errors.New("error trailing data after message")
+	ErrInvalidContentType      = errors.New("error invalid content type")
+	ErrInvalidTopicArn         = errors.New("error invalid topic arn")
+	ErrMissingCredentials      = errors.New("error missing credentials")
+	ErrUnexpectedMessageType   = errors.New("error unexpected message type")
+	ErrConfirmationDenied      = errors.New("error subscription confirmation denied")
+
+	ErrAuthenticatedConfirmationUnsupported = errors.New("error confirmer does not support authenticated confirmation")
 )
+
+// APIError is an ErrorResponse returned by the SNS Query API.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Code       string
+	Message    string
+	RequestId  string
+}
+
+func (e *APIError) Error() string {
+	return "error sns api: " + e.Code + ": " + e.Message
+}
@@ -0,0 +1,40 @@
+package sns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type NotificationHandler interface {
+	HandleNotification(ctx context.Context, msg Notification) error
+}
+
+type NotificationHandlerFunc func(ctx context.Context, msg Notification) error
+
+func (f NotificationHandlerFunc) HandleNotification(ctx context.Context, msg Notification) error {
+	return f(ctx, msg)
+}
+
+// Dispatch returns an http.Handler that verifies SNS messages for snsTopicARN
+// and passes each Notification to h. It responds with 500 when h returns an
+// error so that SNS retries the delivery, or with 503 when h is a Pool that is
+// full or shut down.
+func (m *Middleware) Dispatch(snsTopicARN string, h NotificationHandler) http.Handler {
+	return m.Handler(snsTopicARN)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg, ok := FromContext(r.Context())
+		if !ok {
+			http.Error(w, ErrNotFoundNotification.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := h.HandleNotification(r.Context(), msg); err != nil {
+			if errors.Is(err, ErrQueueFull) || errors.Is(err, ErrShutdown) {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
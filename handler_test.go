@@ -0,0 +1,85 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware_Dispatch(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	b, _ := json.Marshal(signedNotification())
+
+	tests := []struct {
+		name           string
+		checkSignature error
+		handleErr      error
+		wantCalled     bool
+		wantStatusCode int
+	}{
+		{
+			name:           "it returns ok",
+			wantCalled:     true,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "it returns internal server error when handler failed",
+			handleErr:      errors.New("failed"),
+			wantCalled:     true,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "it returns forbidden when CheckSignature failed",
+			checkSignature: ErrInvalidSignature,
+			wantCalled:     false,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			called := false
+			h := NotificationHandlerFunc(func(ctx context.Context, msg Notification) error {
+				called = true
+				if msg.Message != "Hello world!" {
+					t.Errorf("Message = %v, want %v", msg.Message, "Hello world!")
+				}
+				return tt.handleErr
+			})
+
+			m := NewMiddleware()
+			setSubscriber(m, &mockSubscriber{
+				ExpectValidateCertURL: func(certURL string) error {
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return tt.checkSignature
+				},
+			})
+
+			mux := http.NewServeMux()
+			mux.Handle("/", m.Dispatch(topicARN, h))
+
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, "Notification")
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if called != tt.wantCalled {
+				t.Errorf("called = %v, want %v", called, tt.wantCalled)
+			}
+			if resp := w.Result(); resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("Dispatch() = %v, want %v", resp.StatusCode, tt.wantStatusCode)
+			}
+		})
+	}
+}
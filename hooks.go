@@ -0,0 +1,26 @@
+package sns
+
+import "context"
+
+// Hooks are called at each stage of the middleware pipeline. Any of them may be nil.
+type Hooks struct {
+	// BeforeVerify is called with the raw envelope before it is decoded.
+	// Returning an error rejects the request with 403.
+	BeforeVerify func(ctx context.Context, messageType MessageType, body []byte) error
+	// AfterVerify is called with the decoded message and the result of
+	// verifying it. It is not called when the envelope could not be decoded.
+	AfterVerify func(ctx context.Context, msg Message, err error)
+	// OnReject is called for every request the middleware rejects.
+	OnReject func(ctx context.Context, messageType MessageType, outcome Outcome, err error)
+	// OnConfirm is called after a subscription is confirmed, including
+	// asynchronous and deferred confirmations.
+	OnConfirm func(ctx context.Context, msg SubscriptionConfirmation, result *ConfirmSubscriptionResult)
+}
+
+// WithHooks adds h to the hooks of the middleware. Hooks are called in the
+// order they were added.
+func WithHooks(h Hooks) Option {
+	return func(m *Middleware) {
+		m.hooks = append(m.hooks, h)
+	}
+}
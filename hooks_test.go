@@ -0,0 +1,124 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestMiddleware_Subscribe_Hooks(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	errVeto := errors.New("veto")
+
+	tests := map[string]struct {
+		messageType    string
+		msg            interface{}
+		veto           error
+		signatureErr   error
+		confirmErr     error
+		wantEvents     []string
+		wantStatusCode int
+	}{
+		"Notification": {
+			messageType:    "Notification",
+			msg:            Notification{Type: "Notification", MessageId: "1", TopicArn: topicARN},
+			wantEvents:     []string{"BeforeVerify Notification", "AfterVerify sns.Notification 1 <nil>", "handler"},
+			wantStatusCode: http.StatusOK,
+		},
+		"SubscriptionConfirmation": {
+			messageType:    "SubscriptionConfirmation",
+			msg:            SubscriptionConfirmation{Type: "SubscriptionConfirmation", MessageId: "1", TopicArn: topicARN},
+			wantEvents:     []string{"BeforeVerify SubscriptionConfirmation", "AfterVerify sns.SubscriptionConfirmation 1 <nil>", "OnConfirm arn:aws:sns:us-west-2:123456789012:MyTopic:1"},
+			wantStatusCode: http.StatusOK,
+		},
+		"vetoed": {
+			messageType:    "Notification",
+			msg:            Notification{Type: "Notification", MessageId: "1", TopicArn: topicARN},
+			veto:           errVeto,
+			wantEvents:     []string{"BeforeVerify Notification", "OnReject vetoed veto"},
+			wantStatusCode: http.StatusForbidden,
+		},
+		"CheckSignature failed": {
+			messageType:    "Notification",
+			msg:            Notification{Type: "Notification", MessageId: "1", TopicArn: topicARN},
+			signatureErr:   ErrInvalidSignature,
+			wantEvents:     []string{"BeforeVerify Notification", "AfterVerify sns.Notification 1 error invalid signature", "OnReject bad-signature error invalid signature"},
+			wantStatusCode: http.StatusForbidden,
+		},
+		"ConfirmSubscription failed": {
+			messageType:    "SubscriptionConfirmation",
+			msg:            SubscriptionConfirmation{Type: "SubscriptionConfirmation", MessageId: "1", TopicArn: topicARN},
+			confirmErr:     ErrConfirmSubscription,
+			wantEvents:     []string{"BeforeVerify SubscriptionConfirmation", "AfterVerify sns.SubscriptionConfirmation 1 <nil>", "OnReject confirm-failed error confirm subscription"},
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var events []string
+			m := NewMiddleware(WithHooks(Hooks{
+				BeforeVerify: func(ctx context.Context, messageType MessageType, body []byte) error {
+					events = append(events, "BeforeVerify "+messageType.String())
+					return tt.veto
+				},
+				AfterVerify: func(ctx context.Context, msg Message, err error) {
+					events = append(events, fmt.Sprintf("AfterVerify %T %s %v", msg, msg.ID(), err))
+				},
+				OnReject: func(ctx context.Context, messageType MessageType, outcome Outcome, err error) {
+					events = append(events, fmt.Sprintf("OnReject %s %v", outcome, err))
+				},
+				OnConfirm: func(ctx context.Context, msg SubscriptionConfirmation, result *ConfirmSubscriptionResult) {
+					events = append(events, "OnConfirm "+result.SubscriptionArn)
+				},
+			}))
+			setSubscriber(m, &mockSubscriber{
+				ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+					if tt.confirmErr != nil {
+						return nil, tt.confirmErr
+					}
+					return &ConfirmSubscriptionResult{SubscriptionArn: topicARN + ":1"}, nil
+				},
+				ExpectValidateCertURL: func(certURL string) error {
+					events = append(events, "ValidateCertURL")
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return tt.signatureErr
+				},
+			})
+
+			b, _ := json.Marshal(tt.msg)
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, tt.messageType)
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {
+				events = append(events, "handler")
+			}).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("Subscribe() = %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			var got []string
+			for _, e := range events {
+				if e != "ValidateCertURL" || tt.veto != nil {
+					got = append(got, e)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantEvents) {
+				t.Errorf("events = %q, want %q", got, tt.wantEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+// Package adapter holds what the framework adapters have in common.
+package adapter
+
+// ContextKeyNotification is the key the adapters store the verified
+// Notification under in their framework's context.
+const ContextKeyNotification = "sns.notification"
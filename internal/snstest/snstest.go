@@ -0,0 +1,210 @@
+// Package snstest provides signed SNS fixtures and a Client that fetches the
+// test signing certificate from a local TLS server.
+package snstest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+)
+
+const (
+	TopicARN = "arn:aws:sns:us-west-2:123456789012:MyTopic"
+
+	// NotificationBody is signed with testdata/privatekey.pem.
+	NotificationBody = `{` +
+		`"Type":"Notification",` +
+		`"MessageId":"22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",` +
+		`"TopicArn":"arn:aws:sns:us-west-2:123456789012:MyTopic",` +
+		`"Subject":"My First Message",` +
+		`"Message":"Hello world!",` +
+		`"Timestamp":"2012-05-02T00:54:06.655Z",` +
+		`"SignatureVersion":"1",` +
+		`"Signature":"cwMmnINV7NWn5wb4o1faQx9QZBOEpSaJaA86Asdkrpr9C0rdkI/RnyUNl5DrqmueaCiCImuy4Jh0CNeOzqXEdv6WuBjUPbQT/YyAb1h00VVqvjyOvsl2kq+7B3bTfNEahHFZJS2Xh0AtwtWENt159iNnlIRD5NSeVlRyicVv2mgCgK9qxLGGyOFESk43sqUnx5abr0mDR2oFRgbWgwHOly3bQjoaXCfrFYXbmEpz9mMScxoOcRgAUqGVkNLzNBDPU4d9OiBwHxifZBfA6AB3ZxoLm/IZXQJCoK7g44O3NjBCC5nnaMDnHJm1TeSqwVXx8MQQ+8LHhcLbghKkPvo33g==",` +
+		`"SigningCertURL":"https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem",` +
+		`"UnsubscribeURL":"https://sns.us-west-2.amazonaws.com/?Action=Unsubscribe&SubscriptionArn=arn:aws:sns:us-west-2:123456789012:MyTopic:c9135db0-26c4-47ec-8998-413945fb5a96"` +
+		`}`
+
+	// SubscriptionConfirmationBody is signed with testdata/privatekey.pem.
+	SubscriptionConfirmationBody = `{` +
+		`"Type":"SubscriptionConfirmation",` +
+		`"MessageId":"165545c9-2a5c-472c-8df2-7ff2be2b3b1b",` +
+		`"Token":"Ethevee8dae4mie3",` +
+		`"TopicArn":"arn:aws:sns:us-west-2:123456789012:MyTopic",` +
+		`"Message":"You have chosen to subscribe to the topic arn:aws:sns:us-west-2:123456789012:MyTopic.\\nTo confirm the subscription, visit the SubscribeURL included in this message.",` +
+		`"SubscribeURL":"https://sns.us-west-2.amazonaws.com/?Action=ConfirmSubscription&TopicArn=arn:aws:sns:us-west-2:123456789012:MyTopic&Token=Ethevee8dae4mie3",` +
+		`"Timestamp":"2012-04-26T20:45:04.751Z",` +
+		`"SignatureVersion":"1",` +
+		`"Signature":"qVgHxCgP6yNeqk38SUK6bwpw8qkpDoltPdJD2uyMP8nP9VAtz0+Uw+QnOgQ6phAnV21iIPADUa3kDs+BZk5GJ6V1j0p2M1x+alAvQVWvHtbgvYP+dvU/4BFtyW+DEwgeObn3UsRJaWJE+j8e3ssQQu37+5XvBOPzn8h73Js7DxuU1gKdMBuNQNdoenJU6SgN6yVeyyGkqSGrVWJDR36ViwHHq9Sgy0bqV/axqlT7m/UURb2luRSBbIyeD0p5slOYKLLpdt7wyiWG/SjOvxhxo2IpJNTLDNBAVoOG2dynUnaFs1YMU1zz4BUxdoyx1QhUwiXA8HPL3kVSE7bzKNOFtg==",` +
+		`"SigningCertURL":"https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem"` +
+		`}`
+
+	// TamperedNotificationBody carries the signature of NotificationBody with a different Message.
+	TamperedNotificationBody = `{` +
+		`"Type":"Notification",` +
+		`"MessageId":"22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",` +
+		`"TopicArn":"arn:aws:sns:us-west-2:123456789012:MyTopic",` +
+		`"Subject":"My First Message",` +
+		`"Message":"Invalid message",` +
+		`"Timestamp":"2012-05-02T00:54:06.655Z",` +
+		`"SignatureVersion":"1",` +
+		`"Signature":"cwMmnINV7NWn5wb4o1faQx9QZBOEpSaJaA86Asdkrpr9C0rdkI/RnyUNl5DrqmueaCiCImuy4Jh0CNeOzqXEdv6WuBjUPbQT/YyAb1h00VVqvjyOvsl2kq+7B3bTfNEahHFZJS2Xh0AtwtWENt159iNnlIRD5NSeVlRyicVv2mgCgK9qxLGGyOFESk43sqUnx5abr0mDR2oFRgbWgwHOly3bQjoaXCfrFYXbmEpz9mMScxoOcRgAUqGVkNLzNBDPU4d9OiBwHxifZBfA6AB3ZxoLm/IZXQJCoK7g44O3NjBCC5nnaMDnHJm1TeSqwVXx8MQQ+8LHhcLbghKkPvo33g==",` +
+		`"SigningCertURL":"https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem",` +
+		`"UnsubscribeURL":"https://sns.us-west-2.amazonaws.com/?Action=Unsubscribe&SubscriptionArn=arn:aws:sns:us-west-2:123456789012:MyTopic:c9135db0-26c4-47ec-8998-413945fb5a96"` +
+		`}`
+)
+
+// Testdata returns the path of a file in the repository's testdata directory.
+func Testdata(name string) string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "testdata", name)
+}
+
+// NewHTTPClient returns an http.Client that sends every request to a local
+// TLS server serving testdata/public.crt.
+func NewHTTPClient(tb testing.TB) *http.Client {
+	tb.Helper()
+
+	cert, err := os.ReadFile(Testdata("public.crt"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(cert)
+	}))
+	tb.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	hc := srv.Client()
+	transport := hc.Transport
+	hc.Transport = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r = r.Clone(r.Context())
+		r.URL.Scheme = u.Scheme
+		r.URL.Host = u.Host
+		return transport.RoundTrip(r)
+	})
+	return hc
+}
+
+// NewClient returns a Client that verifies signatures with testdata/public.crt.
+func NewClient(tb testing.TB) *sns.Client {
+	tb.Helper()
+
+	return sns.NewClient(sns.WithHTTPClient(NewHTTPClient(tb)))
+}
+
+// Sign returns the signature of signed made with testdata/privatekey.pem, as
+// SignatureVersion 1 messages are signed.
+func Sign(tb testing.TB, signed []byte) string {
+	tb.Helper()
+
+	b, err := os.ReadFile(Testdata("privatekey.pem"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		tb.Fatal("privatekey.pem is not PEM encoded")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	h := sha1.Sum(signed)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA1, h[:])
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// NewRequest returns a Notification request for TopicARN with body.
+func NewRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "text/plain; charset=UTF-8")
+	r.Header.Set(sns.XAmzSnsTopicArn, TopicARN)
+	r.Header.Set(sns.XAmzSnsMessageType, "Notification")
+	return r
+}
+
+// ServeFunc serves r with the Subscribe middleware of an adapter for topicARN
+// in front of a handler that writes the Message of the Notification it gets
+// from the adapter.
+type ServeFunc func(t *testing.T, m *sns.Middleware, topicARN string, r *http.Request) *http.Response
+
+// RunSubscribeTests runs the tests every framework adapter has to pass.
+func RunSubscribeTests(t *testing.T, serve ServeFunc) {
+	t.Helper()
+
+	tests := []struct {
+		name           string
+		topicARN       string
+		body           string
+		wantStatusCode int
+		wantMessage    string
+	}{
+		{
+			name:           "it returns ok",
+			topicARN:       TopicARN,
+			body:           NotificationBody,
+			wantStatusCode: http.StatusOK,
+			wantMessage:    "Hello world!",
+		},
+		{
+			name:           "it returns forbidden when CheckSignature failed",
+			topicARN:       TopicARN,
+			body:           TamperedNotificationBody,
+			wantStatusCode: http.StatusForbidden,
+		},
+		{
+			name:           "it returns forbidden when TopicArn is different",
+			topicARN:       "arn:aws:sns:us-west-2:123456789012:OtherTopic",
+			body:           NotificationBody,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := sns.NewMiddleware(sns.WithClient(NewClient(t)))
+			resp := serve(t, m, tt.topicARN, NewRequest(tt.body))
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("Subscribe() = %v, want %v", resp.StatusCode, tt.wantStatusCode)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if tt.wantMessage != "" && string(body) != tt.wantMessage {
+				t.Errorf("Message = %q, want %q", body, tt.wantMessage)
+			}
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
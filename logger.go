@@ -0,0 +1,60 @@
+package sns
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// LogRecord describes the decision the middleware made about a message. It
+// never carries the Token, SubscribeURL or Signature of the message, and they
+// are redacted from Err.
+type LogRecord struct {
+	MessageId   string
+	TopicArn    string
+	MessageType MessageType
+	Outcome     Outcome
+	Latency     time.Duration
+	Err         string
+}
+
+// Logger records the decisions of the middleware. See the snsslog package for
+// a log/slog implementation.
+type Logger interface {
+	Log(ctx context.Context, rec LogRecord)
+}
+
+type LoggerFunc func(ctx context.Context, rec LogRecord)
+
+func (f LoggerFunc) Log(ctx context.Context, rec LogRecord) {
+	f(ctx, rec)
+}
+
+// WithLogger logs the decision made about every request to l.
+func WithLogger(l Logger) Option {
+	return func(m *Middleware) {
+		m.logger = l
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(context.Context, LogRecord) {}
+
+// redact replaces every secret found in s. The query-escaped form is replaced
+// too, since URLs end up in errors escaped.
+func redact(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		s = strings.ReplaceAll(s, secret, redacted)
+		if q := url.QueryEscape(secret); q != secret {
+			s = strings.ReplaceAll(s, q, redacted)
+		}
+	}
+	return s
+}
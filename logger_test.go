@@ -0,0 +1,134 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware_Subscribe_Logger(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	msg := SubscriptionConfirmation{
+		Type:         "SubscriptionConfirmation",
+		MessageId:    "165545c9-2a5c-472c-8df2-7ff2be2b3b1b",
+		Token:        "Ethevee8dae4mie3",
+		TopicArn:     topicARN,
+		SubscribeURL: "https://sns.us-west-2.amazonaws.com/?Action=ConfirmSubscription&TopicArn=arn:aws:sns:us-west-2:123456789012:MyTopic&Token=Ethevee8dae4mie3",
+		Signature:    "EXAMPLEpH+DcEwjAPg8O9mY8dReBSwksfg2S7WKQcikcNKWLQjwu6A4VbeS0QHVCkhRS7fUQvi2egU3N858fiTDN6bkkOxYDVrY0Ad8L10Hs3zH81mtnPk5uvvolIC1CXGu43obcgFxeL3khZl8IKvO61GWB6jI9b5+gLPoBc1Q=",
+	}
+
+	tests := map[string]struct {
+		confirmErr   error
+		signatureErr error
+		wantOutcome  Outcome
+		wantErr      string
+	}{
+		"ok": {
+			wantOutcome: OutcomeOK,
+		},
+		"confirm-failed": {
+			confirmErr:  fmt.Errorf("Get %q: dial tcp: i/o timeout", msg.SubscribeURL),
+			wantOutcome: OutcomeConfirmFailed,
+			wantErr:     `Get "[REDACTED]": dial tcp: i/o timeout`,
+		},
+		"bad-signature": {
+			signatureErr: fmt.Errorf("%w: %s", ErrInvalidSignature, msg.Signature),
+			wantOutcome:  OutcomeBadSignature,
+			wantErr:      "error invalid signature: [REDACTED]",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var records []LogRecord
+			m := NewMiddleware(WithLogger(LoggerFunc(func(ctx context.Context, rec LogRecord) {
+				records = append(records, rec)
+			})))
+			setSubscriber(m, &mockSubscriber{
+				ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+					return &ConfirmSubscriptionResult{}, tt.confirmErr
+				},
+				ExpectValidateCertURL: func(certURL string) error {
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return tt.signatureErr
+				},
+			})
+
+			b, _ := json.Marshal(msg)
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, "SubscriptionConfirmation")
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+
+			if len(records) != 1 {
+				t.Fatalf("records = %v, want 1", records)
+			}
+			rec := records[0]
+			if rec.MessageId != msg.MessageId || rec.TopicArn != topicARN || rec.MessageType != MessageTypeSubscriptionConfirmation {
+				t.Errorf("LogRecord = %+v", rec)
+			}
+			if rec.Outcome != tt.wantOutcome {
+				t.Errorf("Outcome = %v, want %v", rec.Outcome, tt.wantOutcome)
+			}
+			if rec.Err != tt.wantErr {
+				t.Errorf("Err = %q, want %q", rec.Err, tt.wantErr)
+			}
+			if rec.Latency <= 0 {
+				t.Errorf("Latency = %v, want > 0", rec.Latency)
+			}
+			for _, secret := range []string{msg.Token, msg.Signature} {
+				if strings.Contains(w.Body.String(), secret) {
+					t.Errorf("response body %q contains %q", w.Body.String(), secret)
+				}
+			}
+		})
+	}
+}
+
+func Test_redact(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		s       string
+		secrets []string
+		want    string
+	}{
+		"plain": {
+			s:       "token Ethevee8dae4mie3 is invalid",
+			secrets: []string{"Ethevee8dae4mie3"},
+			want:    "token [REDACTED] is invalid",
+		},
+		"escaped": {
+			s:       "signature a%2Bb%3D is invalid",
+			secrets: []string{"a+b="},
+			want:    "signature [REDACTED] is invalid",
+		},
+		"empty secret": {
+			s:       "nothing to hide",
+			secrets: []string{""},
+			want:    "nothing to hide",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := redact(tt.s, tt.secrets...); got != tt.want {
+				t.Errorf("redact() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
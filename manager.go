@@ -0,0 +1,287 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	apiVersion     = "2010-03-31"
+	apiContentType = "application/x-www-form-urlencoded; charset=utf-8"
+)
+
+const (
+	AttributeRawMessageDelivery = "RawMessageDelivery"
+	AttributeFilterPolicy       = "FilterPolicy"
+	AttributeFilterPolicyScope  = "FilterPolicyScope"
+	AttributeRedrivePolicy      = "RedrivePolicy"
+	AttributeDeliveryPolicy     = "DeliveryPolicy"
+)
+
+type Subscription struct {
+	SubscriptionArn string
+	TopicArn        string
+	Protocol        string
+	Endpoint        string
+	Owner           string
+}
+
+// ConfirmSubscriptionResult is the result of a ConfirmSubscriptionResponse.
+type ConfirmSubscriptionResult struct {
+	SubscriptionArn string
+	RequestId       string
+}
+
+type confirmSubscriptionResponse struct {
+	XMLName         xml.Name `xml:"ConfirmSubscriptionResponse"`
+	SubscriptionArn string   `xml:"ConfirmSubscriptionResult>SubscriptionArn"`
+	RequestId       string   `xml:"ResponseMetadata>RequestId"`
+}
+
+func (r confirmSubscriptionResponse) result() *ConfirmSubscriptionResult {
+	return &ConfirmSubscriptionResult{
+		SubscriptionArn: r.SubscriptionArn,
+		RequestId:       r.RequestId,
+	}
+}
+
+// Manager manages subscriptions through the SNS Query API.
+type Manager struct {
+	httpClient *http.Client
+	retry      RetryPolicy
+	endpoint   string
+	signer     *signer
+}
+
+type ManagerOption func(*Manager)
+
+// WithEndpoint overrides the SNS endpoint, e.g. to use a local stand-in.
+func WithEndpoint(endpoint string) ManagerOption {
+	return func(m *Manager) {
+		m.endpoint = endpoint
+	}
+}
+
+// WithManagerHTTPClient sets the http.Client used to call the SNS API.
+func WithManagerHTTPClient(hc *http.Client) ManagerOption {
+	return func(m *Manager) {
+		m.httpClient = hc
+	}
+}
+
+// WithManagerRetryPolicy sets the policy used to retry API calls.
+// DefaultRetryPolicy is used unless set.
+func WithManagerRetryPolicy(p RetryPolicy) ManagerOption {
+	return func(m *Manager) {
+		m.retry = p
+	}
+}
+
+func NewManager(region string, credentials Credentials, opts ...ManagerOption) *Manager {
+	m := &Manager{
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy(),
+		endpoint:   endpoint(region),
+		signer:     newSigner(region, credentials),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Subscribe subscribes endpoint to topicArn and returns the subscription ARN.
+// ReturnSubscriptionArn is always set, so the ARN is returned even before an
+// HTTP/S endpoint confirms the subscription.
+func (m *Manager) Subscribe(ctx context.Context, topicArn, protocol, endpoint string, attributes map[string]string) (string, error) {
+	params := url.Values{}
+	params.Set("TopicArn", topicArn)
+	params.Set("Protocol", protocol)
+	params.Set("Endpoint", endpoint)
+	params.Set("ReturnSubscriptionArn", "true")
+	setAttributes(params, attributes)
+
+	var resp struct {
+		SubscriptionArn string `xml:"SubscribeResult>SubscriptionArn"`
+	}
+	if err := m.do(ctx, "Subscribe", params, &resp); err != nil {
+		return "", err
+	}
+	return resp.SubscriptionArn, nil
+}
+
+// ConfirmSubscription confirms a subscription with the Token sent in the
+// SubscriptionConfirmation. With authenticateOnUnsubscribe only the topic owner
+// and the subscription owner can unsubscribe the endpoint.
+func (m *Manager) ConfirmSubscription(ctx context.Context, topicArn, token string, authenticateOnUnsubscribe bool) (*ConfirmSubscriptionResult, error) {
+	params := url.Values{}
+	params.Set("TopicArn", topicArn)
+	params.Set("Token", token)
+	if authenticateOnUnsubscribe {
+		params.Set("AuthenticateOnUnsubscribe", "true")
+	}
+
+	var resp confirmSubscriptionResponse
+	if err := m.do(ctx, "ConfirmSubscription", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.result(), nil
+}
+
+func (m *Manager) Unsubscribe(ctx context.Context, subscriptionArn string) error {
+	params := url.Values{}
+	params.Set("SubscriptionArn", subscriptionArn)
+	return m.do(ctx, "Unsubscribe", params, nil)
+}
+
+// ListSubscriptionsByTopic returns a page of subscriptions and the token for the
+// next page, which is empty on the last page.
+func (m *Manager) ListSubscriptionsByTopic(ctx context.Context, topicArn, nextToken string) ([]Subscription, string, error) {
+	params := url.Values{}
+	params.Set("TopicArn", topicArn)
+	if nextToken != "" {
+		params.Set("NextToken", nextToken)
+	}
+
+	var resp struct {
+		Subscriptions []Subscription `xml:"ListSubscriptionsByTopicResult>Subscriptions>member"`
+		NextToken     string         `xml:"ListSubscriptionsByTopicResult>NextToken"`
+	}
+	if err := m.do(ctx, "ListSubscriptionsByTopic", params, &resp); err != nil {
+		return nil, "", err
+	}
+	return resp.Subscriptions, resp.NextToken, nil
+}
+
+func (m *Manager) GetSubscriptionAttributes(ctx context.Context, subscriptionArn string) (map[string]string, error) {
+	params := url.Values{}
+	params.Set("SubscriptionArn", subscriptionArn)
+
+	var resp struct {
+		Entries []struct {
+			Key   string `xml:"key"`
+			Value string `xml:"value"`
+		} `xml:"GetSubscriptionAttributesResult>Attributes>entry"`
+	}
+	if err := m.do(ctx, "GetSubscriptionAttributes", params, &resp); err != nil {
+		return nil, err
+	}
+
+	attributes := make(map[string]string, len(resp.Entries))
+	for _, e := range resp.Entries {
+		attributes[e.Key] = e.Value
+	}
+	return attributes, nil
+}
+
+func (m *Manager) SetSubscriptionAttributes(ctx context.Context, subscriptionArn, name, value string) error {
+	params := url.Values{}
+	params.Set("SubscriptionArn", subscriptionArn)
+	params.Set("AttributeName", name)
+	params.Set("AttributeValue", value)
+	return m.do(ctx, "SetSubscriptionAttributes", params, nil)
+}
+
+func (m *Manager) do(ctx context.Context, action string, params url.Values, out interface{}) error {
+	params.Set("Action", action)
+	params.Set("Version", apiVersion)
+	body := []byte(params.Encode())
+
+	resp, err := m.retry.do(ctx, OpAPI, m.httpClient, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", apiContentType)
+		m.signer.sign(req, body)
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return parseAPIError(resp.StatusCode, b)
+	}
+	if out == nil {
+		return nil
+	}
+	return xml.Unmarshal(b, out)
+}
+
+func endpoint(region string) string {
+	if strings.HasPrefix(region, "cn-") {
+		return "https://sns." + region + ".amazonaws.com.cn/"
+	}
+	return "https://sns." + region + ".amazonaws.com/"
+}
+
+// regionFromARN returns the region of an SNS ARN such as
+// arn:aws:sns:us-west-2:123456789012:MyTopic.
+func regionFromARN(arn string) (string, error) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" || parts[3] == "" {
+		return "", ErrInvalidTopicArn
+	}
+	return parts[3], nil
+}
+
+func parseAPIError(statusCode int, body []byte) error {
+	if err, ok := decodeAPIError(statusCode, body); ok {
+		return err
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		Message:    http.StatusText(statusCode),
+	}
+}
+
+// decodeAPIError decodes an ErrorResponse document. It reports false when body
+// is not one.
+func decodeAPIError(statusCode int, body []byte) (*APIError, bool) {
+	var resp struct {
+		XMLName   xml.Name `xml:"ErrorResponse"`
+		Type      string   `xml:"Error>Type"`
+		Code      string   `xml:"Error>Code"`
+		Message   string   `xml:"Error>Message"`
+		RequestId string   `xml:"RequestId"`
+	}
+	if err := xml.Unmarshal(body, &resp); err != nil || resp.Code == "" {
+		return nil, false
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		Type:       resp.Type,
+		Code:       resp.Code,
+		Message:    resp.Message,
+		RequestId:  resp.RequestId,
+	}, true
+}
+
+// setAttributes encodes attributes as Attributes.entry.N.key/value, sorted by key.
+func setAttributes(params url.Values, attributes map[string]string) {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		n := strconv.Itoa(i + 1)
+		params.Set("Attributes.entry."+n+".key", k)
+		params.Set("Attributes.entry."+n+".value", attributes[k])
+	}
+}
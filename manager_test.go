@@ -0,0 +1,237 @@
+package sns
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManager(t *testing.T) {
+	t.Parallel()
+
+	topicArn := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	subscriptionArn := "arn:aws:sns:us-west-2:123456789012:MyTopic:c9135db0-26c4-47ec-8998-413945fb5a96"
+
+	tests := []struct {
+		name       string
+		call       func(m *Manager) (interface{}, error)
+		status     int
+		response   string
+		wantParams url.Values
+		want       interface{}
+		err        error
+	}{
+		{
+			name: "Subscribe",
+			call: func(m *Manager) (interface{}, error) {
+				return m.Subscribe(context.Background(), topicArn, "https", "https://example.com/sns", map[string]string{
+					AttributeRawMessageDelivery: "true",
+				})
+			},
+			status: http.StatusOK,
+			response: `<SubscribeResponse xmlns="https://sns.amazonaws.com/doc/2010-03-31/">
+  <SubscribeResult>
+    <SubscriptionArn>arn:aws:sns:us-west-2:123456789012:MyTopic:c9135db0-26c4-47ec-8998-413945fb5a96</SubscriptionArn>
+  </SubscribeResult>
+  <ResponseMetadata>
+    <RequestId>c4407779-24a4-56fa-982c-3d927f93a775</RequestId>
+  </ResponseMetadata>
+</SubscribeResponse>`,
+			wantParams: url.Values{
+				"Action":                   {"Subscribe"},
+				"Version":                  {"2010-03-31"},
+				"TopicArn":                 {topicArn},
+				"Protocol":                 {"https"},
+				"Endpoint":                 {"https://example.com/sns"},
+				"ReturnSubscriptionArn":    {"true"},
+				"Attributes.entry.1.key":   {"RawMessageDelivery"},
+				"Attributes.entry.1.value": {"true"},
+			},
+			want: subscriptionArn,
+		},
+		{
+			name: "Unsubscribe",
+			call: func(m *Manager) (interface{}, error) {
+				return nil, m.Unsubscribe(context.Background(), subscriptionArn)
+			},
+			status: http.StatusOK,
+			response: `<UnsubscribeResponse xmlns="https://sns.amazonaws.com/doc/2010-03-31/">
+  <ResponseMetadata>
+    <RequestId>18e0ac39-3776-11df-84c0-b93cc1666b84</RequestId>
+  </ResponseMetadata>
+</UnsubscribeResponse>`,
+			wantParams: url.Values{
+				"Action":          {"Unsubscribe"},
+				"Version":         {"2010-03-31"},
+				"SubscriptionArn": {subscriptionArn},
+			},
+			want: nil,
+		},
+		{
+			name: "ListSubscriptionsByTopic",
+			call: func(m *Manager) (interface{}, error) {
+				subs, next, err := m.ListSubscriptionsByTopic(context.Background(), topicArn, "")
+				return []interface{}{subs, next}, err
+			},
+			status: http.StatusOK,
+			response: `<ListSubscriptionsByTopicResponse xmlns="https://sns.amazonaws.com/doc/2010-03-31/">
+  <ListSubscriptionsByTopicResult>
+    <Subscriptions>
+      <member>
+        <TopicArn>arn:aws:sns:us-west-2:123456789012:MyTopic</TopicArn>
+        <Protocol>https</Protocol>
+        <SubscriptionArn>arn:aws:sns:us-west-2:123456789012:MyTopic:c9135db0-26c4-47ec-8998-413945fb5a96</SubscriptionArn>
+        <Owner>123456789012</Owner>
+        <Endpoint>https://example.com/sns</Endpoint>
+      </member>
+    </Subscriptions>
+    <NextToken>next</NextToken>
+  </ListSubscriptionsByTopicResult>
+  <ResponseMetadata>
+    <RequestId>b9275252-3774-11df-9540-99d0768312d3</RequestId>
+  </ResponseMetadata>
+</ListSubscriptionsByTopicResponse>`,
+			wantParams: url.Values{
+				"Action":   {"ListSubscriptionsByTopic"},
+				"Version":  {"2010-03-31"},
+				"TopicArn": {topicArn},
+			},
+			want: []interface{}{
+				[]Subscription{{
+					SubscriptionArn: subscriptionArn,
+					TopicArn:        topicArn,
+					Protocol:        "https",
+					Endpoint:        "https://example.com/sns",
+					Owner:           "123456789012",
+				}},
+				"next",
+			},
+		},
+		{
+			name: "GetSubscriptionAttributes",
+			call: func(m *Manager) (interface{}, error) {
+				return m.GetSubscriptionAttributes(context.Background(), subscriptionArn)
+			},
+			status: http.StatusOK,
+			response: `<GetSubscriptionAttributesResponse xmlns="https://sns.amazonaws.com/doc/2010-03-31/">
+  <GetSubscriptionAttributesResult>
+    <Attributes>
+      <entry>
+        <key>RawMessageDelivery</key>
+        <value>true</value>
+      </entry>
+      <entry>
+        <key>TopicArn</key>
+        <value>arn:aws:sns:us-west-2:123456789012:MyTopic</value>
+      </entry>
+    </Attributes>
+  </GetSubscriptionAttributesResult>
+  <ResponseMetadata>
+    <RequestId>057f074c-33a7-11df-9540-99d0768312d3</RequestId>
+  </ResponseMetadata>
+</GetSubscriptionAttributesResponse>`,
+			wantParams: url.Values{
+				"Action":          {"GetSubscriptionAttributes"},
+				"Version":         {"2010-03-31"},
+				"SubscriptionArn": {subscriptionArn},
+			},
+			want: map[string]string{
+				"RawMessageDelivery": "true",
+				"TopicArn":           topicArn,
+			},
+		},
+		{
+			name: "SetSubscriptionAttributes",
+			call: func(m *Manager) (interface{}, error) {
+				return nil, m.SetSubscriptionAttributes(context.Background(), subscriptionArn, AttributeFilterPolicy, `{"store":["example_corp"]}`)
+			},
+			status: http.StatusOK,
+			response: `<SetSubscriptionAttributesResponse xmlns="https://sns.amazonaws.com/doc/2010-03-31/">
+  <ResponseMetadata>
+    <RequestId>a8763b99-33a7-11df-a9b7-05d48da6f042</RequestId>
+  </ResponseMetadata>
+</SetSubscriptionAttributesResponse>`,
+			wantParams: url.Values{
+				"Action":          {"SetSubscriptionAttributes"},
+				"Version":         {"2010-03-31"},
+				"SubscriptionArn": {subscriptionArn},
+				"AttributeName":   {"FilterPolicy"},
+				"AttributeValue":  {`{"store":["example_corp"]}`},
+			},
+			want: nil,
+		},
+		{
+			name: "ErrorResponse",
+			call: func(m *Manager) (interface{}, error) {
+				return nil, m.Unsubscribe(context.Background(), subscriptionArn)
+			},
+			status: http.StatusNotFound,
+			response: `<ErrorResponse xmlns="https://sns.amazonaws.com/doc/2010-03-31/">
+  <Error>
+    <Type>Sender</Type>
+    <Code>NotFound</Code>
+    <Message>Subscription does not exist</Message>
+  </Error>
+  <RequestId>9b0a3d3f-5c9a-5ef4-9d8c-2e3a1c7b3e1d</RequestId>
+</ErrorResponse>`,
+			wantParams: url.Values{
+				"Action":          {"Unsubscribe"},
+				"Version":         {"2010-03-31"},
+				"SubscriptionArn": {subscriptionArn},
+			},
+			want: nil,
+			err: &APIError{
+				StatusCode: http.StatusNotFound,
+				Type:       "Sender",
+				Code:       "NotFound",
+				Message:    "Subscription does not exist",
+				RequestId:  "9b0a3d3f-5c9a-5ef4-9d8c-2e3a1c7b3e1d",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				auth := r.Header.Get("Authorization")
+				if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/us-west-2/sns/aws4_request") {
+					t.Errorf("Authorization = %v", auth)
+				}
+				if r.Header.Get("X-Amz-Security-Token") != "token" {
+					t.Errorf("X-Amz-Security-Token = %v", r.Header.Get("X-Amz-Security-Token"))
+				}
+				b, _ := io.ReadAll(r.Body)
+				params, _ := url.ParseQuery(string(b))
+				if !reflect.DeepEqual(params, tt.wantParams) {
+					t.Errorf("params = %v, want %v", params, tt.wantParams)
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.response)
+			}
+
+			srv := httptest.NewServer(http.HandlerFunc(handler))
+			defer srv.Close()
+
+			m := NewManager("us-west-2", Credentials{
+				AccessKeyID:     "AKIDEXAMPLE",
+				SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+				SessionToken:    "token",
+			}, WithEndpoint(srv.URL))
+
+			got, err := tt.call(m)
+			if !reflect.DeepEqual(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
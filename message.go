@@ -2,9 +2,14 @@ package sns
 
 import (
 	"bytes"
+	"encoding/json"
 	"reflect"
+	"time"
 )
 
+// timestampLayout is the format SNS uses for Timestamp.
+const timestampLayout = "2006-01-02T15:04:05.000Z"
+
 var (
 	notificationSignKeys = []string{
 		"Message",
@@ -50,6 +55,13 @@ func NewMessageType(str string) MessageType {
 	return MessageTypeUnknown
 }
 
+func (t MessageType) String() string {
+	if s := messageTypeStrings[t]; s != "" {
+		return s
+	}
+	return "Unknown"
+}
+
 func (t MessageType) signKeys() []string {
 	switch t {
 	case MessageTypeSubscriptionConfirmation, MessageTypeUnsubscribeConfirmation:
@@ -65,8 +77,14 @@ func (t MessageType) sign(m interface{}) []byte {
 	v := reflect.ValueOf(m)
 	for _, key := range t.signKeys() {
 		field := reflect.Indirect(v).FieldByName(key)
+		if !field.IsValid() {
+			continue
+		}
 		val := field.String()
-		if !field.IsValid() || val == "" {
+		if ts, ok := field.Interface().(Timestamp); ok {
+			val = ts.String()
+		}
+		if val == "" {
 			continue
 		}
 		buf.WriteString(key + "\n")
@@ -75,6 +93,110 @@ func (t MessageType) sign(m interface{}) []byte {
 	return buf.Bytes()
 }
 
+// Message is implemented by Notification, SubscriptionConfirmation and
+// UnsubscribeConfirmation.
+type Message interface {
+	MessageType() MessageType
+	ID() string
+	Topic() string
+	SentAt() time.Time
+	MessageSignature() MessageSignature
+}
+
+var (
+	_ Message = Notification{}
+	_ Message = SubscriptionConfirmation{}
+	_ Message = UnsubscribeConfirmation{}
+)
+
+// DecodeMessage decodes data into a Notification, SubscriptionConfirmation or
+// UnsubscribeConfirmation according to its Type. It does not verify the signature.
+func DecodeMessage(data []byte) (Message, error) {
+	var head struct {
+		Type string
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+
+	switch NewMessageType(head.Type) {
+	case MessageTypeNotification:
+		var msg Notification
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	case MessageTypeSubscriptionConfirmation:
+		var msg SubscriptionConfirmation
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	case MessageTypeUnsubscribeConfirmation:
+		var msg UnsubscribeConfirmation
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+	return nil, ErrUnexpectedMessageType
+}
+
+// Timestamp is the time a message was published. It keeps the string it was
+// decoded from, which is what the message signature covers.
+type Timestamp struct {
+	raw  string
+	time time.Time
+}
+
+// NewTimestamp returns t in the format SNS uses.
+func NewTimestamp(t time.Time) Timestamp {
+	t = t.UTC()
+	return Timestamp{raw: t.Format(timestampLayout), time: t}
+}
+
+// ParseTimestamp parses an RFC 3339 timestamp, keeping s as it is.
+func ParseTimestamp(s string) (Timestamp, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	return Timestamp{raw: s, time: t}, nil
+}
+
+func (t Timestamp) Time() time.Time {
+	return t.time
+}
+
+func (t Timestamp) String() string {
+	return t.raw
+}
+
+func (t Timestamp) IsZero() bool {
+	return t.raw == ""
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.raw)
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*t = Timestamp{}
+		return nil
+	}
+	ts, err := ParseTimestamp(s)
+	if err != nil {
+		return err
+	}
+	*t = ts
+	return nil
+}
+
 type MessageSignature struct {
 	Signed           []byte
 	SignatureVersion string
@@ -89,7 +211,7 @@ type SubscriptionConfirmation struct {
 	TopicArn         string
 	Message          string
 	SubscribeURL     string
-	Timestamp        string
+	Timestamp        Timestamp
 	SignatureVersion string
 	Signature        string
 	SigningCertURL   string
@@ -104,13 +226,29 @@ func (m SubscriptionConfirmation) MessageSignature() MessageSignature {
 	}
 }
 
+func (m SubscriptionConfirmation) MessageType() MessageType {
+	return NewMessageType(m.Type)
+}
+
+func (m SubscriptionConfirmation) ID() string {
+	return m.MessageId
+}
+
+func (m SubscriptionConfirmation) Topic() string {
+	return m.TopicArn
+}
+
+func (m SubscriptionConfirmation) SentAt() time.Time {
+	return m.Timestamp.Time()
+}
+
 type Notification struct {
 	Type              string
 	MessageId         string
 	TopicArn          string
 	Subject           string
 	Message           string
-	Timestamp         string
+	Timestamp         Timestamp
 	SignatureVersion  string
 	Signature         string
 	SigningCertURL    string
@@ -130,3 +268,59 @@ func (m Notification) MessageSignature() MessageSignature {
 		SigningCertURL:   m.SigningCertURL,
 	}
 }
+
+func (m Notification) MessageType() MessageType {
+	return NewMessageType(m.Type)
+}
+
+func (m Notification) ID() string {
+	return m.MessageId
+}
+
+func (m Notification) Topic() string {
+	return m.TopicArn
+}
+
+func (m Notification) SentAt() time.Time {
+	return m.Timestamp.Time()
+}
+
+// UnsubscribeConfirmation is sent to the endpoint after its subscription is
+// deleted. Visiting SubscribeURL subscribes the endpoint again.
+type UnsubscribeConfirmation struct {
+	Type             string
+	MessageId        string
+	Token            string
+	TopicArn         string
+	Message          string
+	SubscribeURL     string
+	Timestamp        Timestamp
+	SignatureVersion string
+	Signature        string
+	SigningCertURL   string
+}
+
+func (m UnsubscribeConfirmation) MessageSignature() MessageSignature {
+	return MessageSignature{
+		Signed:           NewMessageType(m.Type).sign(m),
+		SignatureVersion: m.SignatureVersion,
+		Signature:        m.Signature,
+		SigningCertURL:   m.SigningCertURL,
+	}
+}
+
+func (m UnsubscribeConfirmation) MessageType() MessageType {
+	return NewMessageType(m.Type)
+}
+
+func (m UnsubscribeConfirmation) ID() string {
+	return m.MessageId
+}
+
+func (m UnsubscribeConfirmation) Topic() string {
+	return m.TopicArn
+}
+
+func (m UnsubscribeConfirmation) SentAt() time.Time {
+	return m.Timestamp.Time()
+}
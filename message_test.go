@@ -1,9 +1,11 @@
 package sns
 
 import (
+	"encoding/json"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewMessageType(t *testing.T) {
@@ -66,7 +68,7 @@ func TestSubscriptionConfirmation_MessageSignature(t *testing.T) {
 				TopicArn:         "arn:aws:sns:us-west-2:123456789012:MyTopic",
 				Message:          "You have chosen to subscribe to the topic arn:aws:sns:us-west-2:123456789012:MyTopic.\nTo confirm the subscription, visit the SubscribeURL included in this message.",
 				SubscribeURL:     "https://sns.us-west-2.amazonaws.com/?Action=ConfirmSubscription&TopicArn=arn:aws:sns:us-west-2:123456789012:MyTopic&Token=Ethevee8dae4mie3",
-				Timestamp:        "2012-04-26T20:45:04.751Z",
+				Timestamp:        mustParseTimestamp("2012-04-26T20:45:04.751Z"),
 				SignatureVersion: "1",
 				Signature:        "EXAMPLEpH+DcEwjAPg8O9mY8dReBSwksfg2S7WKQcikcNKWLQjwu6A4VbeS0QHVCkhRS7fUQvi2egU3N858fiTDN6bkkOxYDVrY0Ad8L10Hs3zH81mtnPk5uvvolIC1CXGu43obcgFxeL3khZl8IKvO61GWB6jI9b5+gLPoBc1Q=",
 				SigningCertURL:   "https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem",
@@ -121,7 +123,7 @@ func TestNotification_MessageSignature(t *testing.T) {
 				TopicArn:         "arn:aws:sns:us-west-2:123456789012:MyTopic",
 				Subject:          "My First Message",
 				Message:          "Hello world!",
-				Timestamp:        "2012-05-02T00:54:06.655Z",
+				Timestamp:        mustParseTimestamp("2012-05-02T00:54:06.655Z"),
 				SignatureVersion: "1",
 				Signature:        "EXAMPLEpH+DcEwjAPg8O9mY8dReBSwksfg2S7WKQcikcNKWLQjwu6A4VbeS0QHVCkhRS7fUQvi2egU3N858fiTDN6bkkOxYDVrY0Ad8L10Hs3zH81mtnPk5uvvolIC1CXGu43obcgFxeL3khZl8IKvO61GWB6jI9b5+gLPoBc1Q=",
 				SigningCertURL:   "https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem",
@@ -160,3 +162,175 @@ func TestNotification_MessageSignature(t *testing.T) {
 		})
 	}
 }
+
+func TestUnsubscribeConfirmation_MessageSignature(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		message UnsubscribeConfirmation
+		want    MessageSignature
+	}{
+		"success": {
+			message: UnsubscribeConfirmation{
+				Type:             "UnsubscribeConfirmation",
+				MessageId:        "47138184-6831-46b8-8f7c-afc488602d7d",
+				Token:            "Ethevee8dae4mie3",
+				TopicArn:         "arn:aws:sns:us-west-2:123456789012:MyTopic",
+				Message:          "You have chosen to deactivate subscription arn:aws:sns:us-west-2:123456789012:MyTopic:2bcfbf39-05c3-41de-beaa-fcfcc21c8f55.\nTo cancel this operation and restore the subscription, visit the SubscribeURL included in this message.",
+				SubscribeURL:     "https://sns.us-west-2.amazonaws.com/?Action=ConfirmSubscription&TopicArn=arn:aws:sns:us-west-2:123456789012:MyTopic&Token=Ethevee8dae4mie3",
+				Timestamp:        mustParseTimestamp("2012-04-26T20:06:41.580Z"),
+				SignatureVersion: "1",
+				Signature:        "EXAMPLEHXgJm...",
+				SigningCertURL:   "https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem",
+			},
+			want: MessageSignature{
+				Signed: []byte(strings.Join([]string{
+					"Message",
+					"You have chosen to deactivate subscription arn:aws:sns:us-west-2:123456789012:MyTopic:2bcfbf39-05c3-41de-beaa-fcfcc21c8f55.\nTo cancel this operation and restore the subscription, visit the SubscribeURL included in this message.",
+					"MessageId",
+					"47138184-6831-46b8-8f7c-afc488602d7d",
+					"SubscribeURL",
+					"https://sns.us-west-2.amazonaws.com/?Action=ConfirmSubscription&TopicArn=arn:aws:sns:us-west-2:123456789012:MyTopic&Token=Ethevee8dae4mie3",
+					"Timestamp",
+					"2012-04-26T20:06:41.580Z",
+					"Token",
+					"Ethevee8dae4mie3",
+					"TopicArn",
+					"arn:aws:sns:us-west-2:123456789012:MyTopic",
+					"Type",
+					"UnsubscribeConfirmation\n",
+				}, "\n")),
+				SignatureVersion: "1",
+				Signature:        "EXAMPLEHXgJm...",
+				SigningCertURL:   "https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.message.MessageSignature(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MessageSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	timestamp := mustParseTimestamp("2012-05-02T00:54:06.655Z")
+
+	tests := map[string]struct {
+		data    string
+		want    Message
+		wantErr error
+	}{
+		"Notification": {
+			data: `{"Type":"Notification","MessageId":"1","TopicArn":"` + topicARN + `","Message":"Hello world!","Timestamp":"2012-05-02T00:54:06.655Z"}`,
+			want: Notification{Type: "Notification", MessageId: "1", TopicArn: topicARN, Message: "Hello world!", Timestamp: timestamp},
+		},
+		"SubscriptionConfirmation": {
+			data: `{"Type":"SubscriptionConfirmation","MessageId":"2","TopicArn":"` + topicARN + `","Token":"token","Timestamp":"2012-05-02T00:54:06.655Z"}`,
+			want: SubscriptionConfirmation{Type: "SubscriptionConfirmation", MessageId: "2", TopicArn: topicARN, Token: "token", Timestamp: timestamp},
+		},
+		"UnsubscribeConfirmation": {
+			data: `{"Type":"UnsubscribeConfirmation","MessageId":"3","TopicArn":"` + topicARN + `","Token":"token","Timestamp":"2012-05-02T00:54:06.655Z"}`,
+			want: UnsubscribeConfirmation{Type: "UnsubscribeConfirmation", MessageId: "3", TopicArn: topicARN, Token: "token", Timestamp: timestamp},
+		},
+		"unknown type": {
+			data:    `{"Type":"Unknown"}`,
+			wantErr: ErrUnexpectedMessageType,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := DecodeMessage([]byte(tt.data))
+			if err != tt.wantErr {
+				t.Fatalf("DecodeMessage() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("DecodeMessage() = %#v, want %#v", got, tt.want)
+			}
+			if tt.want == nil {
+				return
+			}
+			if got.MessageType() != tt.want.MessageType() || got.Topic() != topicARN || !got.SentAt().Equal(timestamp.Time()) || got.ID() == "" {
+				t.Errorf("Message = %v %v %v %v", got.MessageType(), got.ID(), got.Topic(), got.SentAt())
+			}
+		})
+	}
+}
+
+func mustParseTimestamp(s string) Timestamp {
+	ts, err := ParseTimestamp(s)
+	if err != nil {
+		panic(err)
+	}
+	return ts
+}
+
+func TestTimestamp(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "milliseconds",
+			data: `"2012-05-02T00:54:06.655Z"`,
+			want: time.Date(2012, 5, 2, 0, 54, 6, 655000000, time.UTC),
+		},
+		{
+			name: "seconds",
+			data: `"2012-05-02T00:54:06Z"`,
+			want: time.Date(2012, 5, 2, 0, 54, 6, 0, time.UTC),
+		},
+		{
+			name: "microseconds with offset",
+			data: `"2012-05-02T09:54:06.655001+09:00"`,
+			want: time.Date(2012, 5, 2, 0, 54, 6, 655001000, time.UTC),
+		},
+		{
+			name:    "invalid",
+			data:    `"yesterday"`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var msg Notification
+			err := json.Unmarshal([]byte(`{"Type":"Notification","Timestamp":`+tt.data+`}`), &msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !msg.Timestamp.Time().Equal(tt.want) {
+				t.Errorf("Time() = %v, want %v", msg.Timestamp.Time(), tt.want)
+			}
+			// The signature covers the timestamp as it was received.
+			want := "Timestamp\n" + strings.Trim(tt.data, `"`) + "\nType\nNotification\n"
+			if got := string(msg.MessageSignature().Signed); got != want {
+				t.Errorf("Signed = %q, want %q", got, want)
+			}
+			if b, _ := json.Marshal(msg.Timestamp); string(b) != tt.data {
+				t.Errorf("MarshalJSON() = %s, want %s", b, tt.data)
+			}
+		})
+	}
+}
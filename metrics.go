@@ -0,0 +1,58 @@
+package sns
+
+import "time"
+
+// Outcome is the decision the middleware made about a message.
+type Outcome string
+
+const (
+	OutcomeOK             Outcome = "ok"
+	OutcomeBadRequest     Outcome = "bad-request"
+	OutcomeBadJSON        Outcome = "bad-json"
+	OutcomeInvalidCertURL Outcome = "invalid-cert-url"
+	OutcomeBadSignature   Outcome = "bad-signature"
+	OutcomeTopicMismatch  Outcome = "topic-mismatch"
+	OutcomeUnknownType    Outcome = "unknown-type"
+	OutcomeDenied         Outcome = "denied"
+	OutcomeDeferred       Outcome = "deferred"
+	OutcomeConfirmFailed  Outcome = "confirm-failed"
+	OutcomeUnavailable    Outcome = "unavailable"
+	OutcomeVetoed         Outcome = "vetoed"
+	OutcomeError          Outcome = "error"
+)
+
+// Metrics receives measurements from Middleware and Client. See the snsprom
+// package for a Prometheus implementation.
+type Metrics interface {
+	// ObserveMessage is called once per request with the topic ARN the
+	// middleware was created for, so that the label set stays bounded.
+	ObserveMessage(topicArn string, messageType MessageType, outcome Outcome)
+	ObserveCertFetch(d time.Duration, err error)
+	ObserveSignatureVerification(d time.Duration, err error)
+	ObserveCertCache(hit bool)
+}
+
+// WithMetrics reports the outcome of every request to mt.
+func WithMetrics(mt Metrics) Option {
+	return func(m *Middleware) {
+		m.metrics = mt
+	}
+}
+
+// WithClientMetrics reports cert fetches, signature verifications and cert
+// cache lookups to mt.
+func WithClientMetrics(mt Metrics) ClientOption {
+	return func(c *Client) {
+		c.metrics = mt
+	}
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveMessage(string, MessageType, Outcome) {}
+
+func (nopMetrics) ObserveCertFetch(time.Duration, error) {}
+
+func (nopMetrics) ObserveSignatureVerification(time.Duration, error) {}
+
+func (nopMetrics) ObserveCertCache(bool) {}
@@ -0,0 +1,216 @@
+package sns
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type observedMessage struct {
+	topicArn    string
+	messageType MessageType
+	outcome     Outcome
+}
+
+type mockMetrics struct {
+	mu             sync.Mutex
+	messages       []observedMessage
+	certFetches    int
+	verifications  int
+	cacheHits      int
+	cacheMisses    int
+	verifyFailures int
+}
+
+func (m *mockMetrics) ObserveMessage(topicArn string, messageType MessageType, outcome Outcome) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, observedMessage{topicArn, messageType, outcome})
+}
+
+func (m *mockMetrics) ObserveCertFetch(d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.certFetches++
+}
+
+func (m *mockMetrics) ObserveSignatureVerification(d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.verifications++
+	if err != nil {
+		m.verifyFailures++
+	}
+}
+
+func (m *mockMetrics) ObserveCertCache(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+func TestMiddleware_Subscribe_Metrics(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	notification, _ := json.Marshal(Notification{Type: "Notification", TopicArn: topicARN})
+	confirmation, _ := json.Marshal(SubscriptionConfirmation{Type: "SubscriptionConfirmation", TopicArn: topicARN})
+
+	tests := map[string]struct {
+		topicArn       string
+		messageType    string
+		body           []byte
+		validateErr    error
+		signatureErr   error
+		confirmErr     error
+		wantType       MessageType
+		wantOutcome    Outcome
+		wantStatusCode int
+	}{
+		"ok": {
+			topicArn:       topicARN,
+			messageType:    "Notification",
+			body:           notification,
+			wantType:       MessageTypeNotification,
+			wantOutcome:    OutcomeOK,
+			wantStatusCode: http.StatusOK,
+		},
+		"topic-mismatch": {
+			topicArn:       "arn:aws:sns:us-west-2:123456789012:Other",
+			messageType:    "Notification",
+			body:           notification,
+			wantType:       MessageTypeNotification,
+			wantOutcome:    OutcomeTopicMismatch,
+			wantStatusCode: http.StatusForbidden,
+		},
+		"bad-json": {
+			topicArn:       topicARN,
+			messageType:    "Notification",
+			body:           []byte("{"),
+			wantType:       MessageTypeNotification,
+			wantOutcome:    OutcomeBadJSON,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		"invalid-cert-url": {
+			topicArn:       topicARN,
+			messageType:    "Notification",
+			body:           notification,
+			validateErr:    ErrInvalidCertURLHost,
+			wantType:       MessageTypeNotification,
+			wantOutcome:    OutcomeInvalidCertURL,
+			wantStatusCode: http.StatusForbidden,
+		},
+		"bad-signature": {
+			topicArn:       topicARN,
+			messageType:    "Notification",
+			body:           notification,
+			signatureErr:   ErrInvalidSignature,
+			wantType:       MessageTypeNotification,
+			wantOutcome:    OutcomeBadSignature,
+			wantStatusCode: http.StatusForbidden,
+		},
+		"confirm-failed": {
+			topicArn:       topicARN,
+			messageType:    "SubscriptionConfirmation",
+			body:           confirmation,
+			confirmErr:     ErrConfirmSubscription,
+			wantType:       MessageTypeSubscriptionConfirmation,
+			wantOutcome:    OutcomeConfirmFailed,
+			wantStatusCode: http.StatusForbidden,
+		},
+		"unknown-type": {
+			topicArn:       topicARN,
+			messageType:    "Other",
+			body:           notification,
+			wantType:       MessageTypeUnknown,
+			wantOutcome:    OutcomeUnknownType,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			metrics := &mockMetrics{}
+			m := NewMiddleware(WithMetrics(metrics))
+			setSubscriber(m, &mockSubscriber{
+				ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+					return &ConfirmSubscriptionResult{}, tt.confirmErr
+				},
+				ExpectValidateCertURL: func(certURL string) error {
+					return tt.validateErr
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return tt.signatureErr
+				},
+			})
+
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(tt.body))
+			req.Header.Set(XAmzSnsTopicArn, tt.topicArn)
+			req.Header.Set(XAmzSnsMessageType, tt.messageType)
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("Subscribe() = %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			want := []observedMessage{{topicARN, tt.wantType, tt.wantOutcome}}
+			if len(metrics.messages) != 1 || metrics.messages[0] != want[0] {
+				t.Errorf("ObserveMessage() = %v, want %v", metrics.messages, want)
+			}
+		})
+	}
+}
+
+func TestClient_CheckSignature_CertCache(t *testing.T) {
+	t.Parallel()
+
+	cert, err := os.ReadFile("testdata/public.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write(cert)
+	}))
+	defer srv.Close()
+
+	msg := signedNotification(func(msg *Notification) {
+		msg.SigningCertURL = srv.URL
+	})
+
+	metrics := &mockMetrics{}
+	c := NewClient(WithClientMetrics(metrics))
+	if err := c.CheckSignature(msg.MessageSignature()); err != nil {
+		t.Fatalf("CheckSignature() = %v", err)
+	}
+	msg.Message = "Invalid message"
+	if err := c.CheckSignature(msg.MessageSignature()); err != ErrInvalidSignature {
+		t.Fatalf("CheckSignature() = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Errorf("fetches = %d, want 1", got)
+	}
+	if metrics.cacheMisses != 1 || metrics.cacheHits != 1 {
+		t.Errorf("cache misses = %d, hits = %d, want 1, 1", metrics.cacheMisses, metrics.cacheHits)
+	}
+	if metrics.certFetches != 1 {
+		t.Errorf("cert fetches = %d, want 1", metrics.certFetches)
+	}
+	if metrics.verifications != 2 || metrics.verifyFailures != 1 {
+		t.Errorf("verifications = %d, failures = %d, want 2, 1", metrics.verifications, metrics.verifyFailures)
+	}
+}
@@ -1,87 +1,561 @@
 package sns
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
+	"strings"
+	"time"
 )
 
 const (
 	XAmzSnsMessageType string = "x-amz-sns-message-type"
 	XAmzSnsTopicArn    string = "x-amz-sns-topic-arn"
+
+	XAmzSnsMessageId       string = "x-amz-sns-message-id"
+	XAmzSnsSubscriptionArn string = "x-amz-sns-subscription-arn"
+)
+
+const (
+	// DefaultMaxBodySize allows the 256KB SNS payload plus room for the envelope.
+	DefaultMaxBodySize int64 = (256 + 64) * 1024
+
+	snsContentType = "text/plain"
+	snsCharset     = "utf-8"
 )
 
-type subscriber interface {
-	ConfirmSubscription(msg SubscriptionConfirmation) (string, error)
+// Verifier checks that a message was signed by SNS. Client implements it by
+// downloading the signing cert; other implementations may keep certs elsewhere.
+type Verifier interface {
 	ValidateCertURL(certURL string) error
 	CheckSignature(ms MessageSignature) error
 }
 
+// contextVerifier is implemented by Verifiers that use the request context,
+// such as Client.
+type contextVerifier interface {
+	CheckSignatureContext(ctx context.Context, ms MessageSignature) error
+}
+
+// Confirmer confirms subscriptions.
+type Confirmer interface {
+	ConfirmSubscription(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error)
+}
+
+// contextConfirmer and contextAuthenticatedConfirmer are implemented by
+// Confirmers that use the request context, such as Client.
+type contextConfirmer interface {
+	ConfirmSubscriptionContext(ctx context.Context, msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error)
+}
+
+type contextAuthenticatedConfirmer interface {
+	ConfirmSubscriptionAuthenticatedContext(ctx context.Context, msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error)
+}
+
+// AuthenticatedConfirmer confirms subscriptions with AuthenticateOnUnsubscribe.
+// The Confirmer of the middleware must implement it to use
+// WithAuthenticatedConfirmation.
+type AuthenticatedConfirmer interface {
+	ConfirmSubscriptionAuthenticated(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error)
+}
+
+var (
+	_ Verifier               = (*Client)(nil)
+	_ Confirmer              = (*Client)(nil)
+	_ AuthenticatedConfirmer = (*Client)(nil)
+
+	_ contextVerifier               = (*Client)(nil)
+	_ contextConfirmer              = (*Client)(nil)
+	_ contextAuthenticatedConfirmer = (*Client)(nil)
+)
+
 type Middleware struct {
-	subscriber subscriber
+	verifier    Verifier
+	confirmer   Confirmer
+	maxBodySize int64
+	strict      bool
+	confirmed   []func(msg SubscriptionConfirmation)
+
+	authenticateOnUnsubscribe bool
+
+	onSubscriptionConfirmation SubscriptionConfirmationPolicy
+	pending                    PendingStore
+	onUnsubscribeConfirmation  []func(ctx context.Context, msg UnsubscribeConfirmation) error
+
+	async                  *asyncConfirmer
+	onConfirmationComplete []func(token string, status ConfirmationStatus)
+
+	pool *Pool
+
+	metrics Metrics
+	tracer  Tracer
+	logger  Logger
+	hooks   []Hooks
+	audit   AuditSink
+}
+
+type Option func(*Middleware)
+
+// WithClient sets the Client used to verify messages and confirm subscriptions.
+func WithClient(c *Client) Option {
+	return func(m *Middleware) {
+		m.verifier = c
+		m.confirmer = c
+	}
+}
+
+// WithVerifier sets the Verifier used to verify messages.
+func WithVerifier(v Verifier) Option {
+	return func(m *Middleware) {
+		m.verifier = v
+	}
+}
+
+// WithConfirmer sets the Confirmer used to confirm subscriptions.
+func WithConfirmer(c Confirmer) Option {
+	return func(m *Middleware) {
+		m.confirmer = c
+	}
+}
+
+// WithMaxBodySize limits the size of the request body read by the middleware.
+func WithMaxBodySize(n int64) Option {
+	return func(m *Middleware) {
+		m.maxBodySize = n
+	}
 }
 
-func NewMiddleware() *Middleware {
-	return &Middleware{
-		subscriber: NewClient(),
+// WithStrict rejects unknown JSON fields and requests whose Content-Type is not
+// the `text/plain; charset=UTF-8` sent by SNS.
+func WithStrict() Option {
+	return func(m *Middleware) {
+		m.strict = true
 	}
 }
 
+// WithAuthenticatedConfirmation confirms subscriptions with the SNS
+// ConfirmSubscription action and AuthenticateOnUnsubscribe instead of visiting
+// the SubscribeURL. The Client needs credentials, see WithCredentials, and a
+// custom Confirmer must implement AuthenticatedConfirmer.
+func WithAuthenticatedConfirmation() Option {
+	return func(m *Middleware) {
+		m.authenticateOnUnsubscribe = true
+	}
+}
+
+// WithOnUnsubscribeConfirmation calls f with every verified
+// UnsubscribeConfirmation. The middleware responds with 500 when f returns an
+// error, and with 200 otherwise.
+func WithOnUnsubscribeConfirmation(f func(ctx context.Context, msg UnsubscribeConfirmation) error) Option {
+	return func(m *Middleware) {
+		m.onUnsubscribeConfirmation = append(m.onUnsubscribeConfirmation, f)
+	}
+}
+
+// WithRegistrar reports confirmed subscriptions to r.
+func WithRegistrar(r *Registrar) Option {
+	return func(m *Middleware) {
+		m.confirmed = append(m.confirmed, r.confirmed)
+	}
+}
+
+func NewMiddleware(opts ...Option) *Middleware {
+	c := NewClient()
+	m := &Middleware{
+		verifier:    c,
+		confirmer:   c,
+		maxBodySize: DefaultMaxBodySize,
+		pending:     NewMemoryPendingStore(),
+		metrics:     nopMetrics{},
+		tracer:      nopTracer{},
+		logger:      nopLogger{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.async != nil {
+		go m.runAsyncConfirmer()
+	}
+	return m
+}
+
 func (m *Middleware) Subscribe(snsTopicARN string) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
+		return m.Handler(snsTopicARN)(next).ServeHTTP
+	}
+}
+
+// Handler returns middleware in the func(http.Handler) http.Handler form used by
+// standard middleware chains.
+func (m *Middleware) Handler(snsTopicARN string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			msgType := NewMessageType(r.Header.Get(XAmzSnsMessageType))
 			topicArn := r.Header.Get(XAmzSnsTopicArn)
+			info := SpanInfo{
+				TopicArn:    snsTopicARN,
+				MessageId:   r.Header.Get(XAmzSnsMessageId),
+				MessageType: msgType,
+			}
+			var (
+				body    []byte
+				secrets []string
+			)
+			audit := func(outcome Outcome, err error) error {
+				if m.audit == nil {
+					return nil
+				}
+				rec := AuditRecord{
+					Time:           time.Now(),
+					TopicArn:       topicArn,
+					MessageType:    msgType,
+					MessageId:      info.MessageId,
+					Outcome:        outcome,
+					Envelope:       redactEnvelope(body, secrets...),
+					EnvelopeSHA256: envelopeDigest(body),
+				}
+				if err != nil {
+					rec.Err = redact(err.Error(), secrets...)
+				}
+				return m.audit.Audit(r.Context(), rec)
+			}
+			observe := func(outcome Outcome, err error) {
+				m.metrics.ObserveMessage(snsTopicARN, msgType, outcome)
+				rec := LogRecord{
+					MessageId:   info.MessageId,
+					TopicArn:    topicArn,
+					MessageType: msgType,
+					Outcome:     outcome,
+					Latency:     time.Since(start),
+				}
+				if err != nil {
+					rec.Err = redact(err.Error(), secrets...)
+				}
+				m.logger.Log(r.Context(), rec)
+			}
+			// decide audits best-effort: the response for rejected messages and
+			// subscription confirmations does not depend on the audit sink.
+			decide := func(outcome Outcome, err error) {
+				_ = audit(outcome, err)
+				observe(outcome, err)
+			}
+			rejected := func(outcome Outcome, err error) {
+				decide(outcome, err)
+				for _, h := range m.hooks {
+					if h.OnReject != nil {
+						h.OnReject(r.Context(), msgType, outcome, err)
+					}
+				}
+			}
+			reject := func(err error, code int, outcome Outcome) {
+				rejected(outcome, err)
+				http.Error(w, redact(err.Error(), secrets...), code)
+			}
+			rejectVerify := func(outcome Outcome, err error) {
+				if outcome == OutcomeTopicMismatch {
+					rejected(outcome, err)
+					http.Error(w, "invalid SNS TopicArn", http.StatusForbidden)
+					return
+				}
+				reject(err, outcomeStatus(outcome), outcome)
+			}
+
 			if topicArn != snsTopicARN {
+				rejected(OutcomeTopicMismatch, ErrInvalidTopicArn)
 				http.Error(w, "invalid SNS TopicArn", http.StatusForbidden)
 				return
 			}
 
-			var ctx context.Context
-			switch NewMessageType(r.Header.Get(XAmzSnsMessageType)) {
+			if m.strict {
+				if err := checkContentType(r.Header.Get("Content-Type")); err != nil {
+					reject(err, http.StatusUnsupportedMediaType, OutcomeBadRequest)
+					return
+				}
+			}
+
+			body, err := m.readBody(r)
+			if errors.Is(err, ErrRequestBodyTooLarge) {
+				reject(err, http.StatusRequestEntityTooLarge, OutcomeBadRequest)
+				return
+			}
+			if err != nil {
+				reject(err, http.StatusBadRequest, OutcomeBadRequest)
+				return
+			}
+
+			for _, h := range m.hooks {
+				if h.BeforeVerify == nil {
+					continue
+				}
+				if err := h.BeforeVerify(r.Context(), msgType, body); err != nil {
+					reject(err, http.StatusForbidden, OutcomeVetoed)
+					return
+				}
+			}
+
+			switch msgType {
 			case MessageTypeSubscriptionConfirmation:
 				var msg SubscriptionConfirmation
-				if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
-					http.Error(w, err.Error(), http.StatusBadRequest)
+				outcome, err := m.verify(r.Context(), info, body, &msg)
+				secrets = []string{msg.SubscribeURL, msg.Token, msg.Signature}
+				if msg.MessageId != "" {
+					info.MessageId = msg.MessageId
+				}
+				m.afterVerify(r.Context(), outcome, msg, err)
+				if err != nil {
+					rejectVerify(outcome, err)
 					return
 				}
-				if err := m.subscriber.ValidateCertURL(msg.SigningCertURL); err != nil {
-					http.Error(w, err.Error(), http.StatusForbidden)
+				decision := DecisionConfirm
+				if m.onSubscriptionConfirmation != nil {
+					d, err := m.onSubscriptionConfirmation(r.Context(), msg)
+					if err != nil {
+						reject(err, http.StatusInternalServerError, OutcomeError)
+						return
+					}
+					decision = d
+				}
+				switch decision {
+				case DecisionDeny:
+					rejected(OutcomeDenied, ErrConfirmationDenied)
+					http.Error(w, "subscription confirmation denied", http.StatusForbidden)
+					return
+				case DecisionDefer:
+					if err := m.pending.Save(r.Context(), msg); err != nil {
+						reject(err, http.StatusInternalServerError, OutcomeError)
+						return
+					}
+					decide(OutcomeDeferred, nil)
+					w.WriteHeader(http.StatusOK)
+					return
+				case DecisionConfirm:
+				default:
+					reject(ErrInvalidDecision, http.StatusInternalServerError, OutcomeError)
 					return
 				}
-				if err := m.subscriber.CheckSignature(msg.MessageSignature()); err != nil {
-					http.Error(w, err.Error(), http.StatusForbidden)
+				if m.async != nil {
+					if err := m.async.enqueue(msg); err != nil {
+						reject(err, http.StatusServiceUnavailable, OutcomeUnavailable)
+						return
+					}
+					decide(OutcomeOK, nil)
+					w.WriteHeader(http.StatusOK)
 					return
 				}
-				body, err := m.subscriber.ConfirmSubscription(msg)
+				err = trace(r.Context(), m.tracer, StageConfirm, info, func(ctx context.Context) error {
+					_, err := m.confirm(ctx, msg)
+					return err
+				})
 				if err != nil {
-					http.Error(w, err.Error(), http.StatusForbidden)
+					rejected(OutcomeConfirmFailed, err)
+					http.Error(w, ErrConfirmSubscription.Error(), http.StatusForbidden)
 					return
 				}
+				decide(OutcomeOK, nil)
 				w.WriteHeader(http.StatusOK)
-				fmt.Fprintln(w, body)
 				return
 			case MessageTypeNotification:
 				var msg Notification
-				if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
-					http.Error(w, err.Error(), http.StatusBadRequest)
+				outcome, err := m.verify(r.Context(), info, body, &msg)
+				secrets = []string{msg.Signature, msg.UnsubscribeURL}
+				if msg.MessageId != "" {
+					info.MessageId = msg.MessageId
+				}
+				m.afterVerify(r.Context(), outcome, msg, err)
+				if err != nil {
+					rejectVerify(outcome, err)
 					return
 				}
-				if err := m.subscriber.ValidateCertURL(msg.SigningCertURL); err != nil {
-					http.Error(w, err.Error(), http.StatusForbidden)
+				ctx := NewContext(r.Context(), msg)
+				ctx = NewRawEnvelopeContext(ctx, body)
+				ctx = NewVerificationInfoContext(ctx, VerificationInfo{
+					TopicArn:         msg.TopicArn,
+					SubscriptionArn:  r.Header.Get(XAmzSnsSubscriptionArn),
+					MessageId:        r.Header.Get(XAmzSnsMessageId),
+					SigningCertURL:   msg.SigningCertURL,
+					SignatureVersion: msg.SignatureVersion,
+					VerifiedAt:       time.Now(),
+				})
+				ctx = m.tracer.Extract(ctx, msg)
+				if err := audit(OutcomeOK, nil); err != nil {
+					observe(OutcomeError, err)
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 					return
 				}
-				if err := m.subscriber.CheckSignature(msg.MessageSignature()); err != nil {
-					http.Error(w, err.Error(), http.StatusForbidden)
+				// The record is written before the notification is queued, so
+				// that a failed audit is never acknowledged after the handler
+				// got the message. A full queue adds an OutcomeUnavailable record.
+				if m.pool != nil {
+					if err := m.pool.HandleNotification(ctx, msg); err != nil {
+						reject(err, http.StatusServiceUnavailable, OutcomeUnavailable)
+						return
+					}
+				}
+				observe(OutcomeOK, nil)
+				if m.pool != nil {
+					w.WriteHeader(http.StatusOK)
 					return
 				}
-				ctx = SetNotification(r, msg)
+				ctx, span := m.tracer.Start(ctx, StageHandler, info)
+				defer span.End(nil)
+				r = r.WithContext(ctx)
+			case MessageTypeUnsubscribeConfirmation:
+				var msg UnsubscribeConfirmation
+				outcome, err := m.verify(r.Context(), info, body, &msg)
+				secrets = []string{msg.SubscribeURL, msg.Token, msg.Signature}
+				if msg.MessageId != "" {
+					info.MessageId = msg.MessageId
+				}
+				m.afterVerify(r.Context(), outcome, msg, err)
+				if err != nil {
+					rejectVerify(outcome, err)
+					return
+				}
+				for _, f := range m.onUnsubscribeConfirmation {
+					if err := f(r.Context(), msg); err != nil {
+						reject(err, http.StatusInternalServerError, OutcomeError)
+						return
+					}
+				}
+				decide(OutcomeOK, nil)
+				w.WriteHeader(http.StatusOK)
+				return
 			default:
+				rejected(OutcomeUnknownType, ErrUnexpectedMessageType)
 				http.Error(w, "unexpected message type", http.StatusForbidden)
 				return
 			}
 
-			next(w, r.WithContext(ctx))
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// verify decodes body into msg, validates its cert URL, checks its signature
+// and that it was sent to the topic of info, tracing each stage. It returns the outcome of the stage that failed.
+func (m *Middleware) verify(ctx context.Context, info SpanInfo, body []byte, msg Message) (Outcome, error) {
+	if err := trace(ctx, m.tracer, StageDecode, info, func(ctx context.Context) error {
+		return m.decode(body, msg)
+	}); err != nil {
+		return OutcomeBadJSON, err
+	}
+
+	ms := msg.MessageSignature()
+	if err := trace(ctx, m.tracer, StageValidateCertURL, info, func(ctx context.Context) error {
+		return m.verifier.ValidateCertURL(ms.SigningCertURL)
+	}); err != nil {
+		return OutcomeInvalidCertURL, err
+	}
+
+	if err := trace(ctx, m.tracer, StageCheckSignature, info, func(ctx context.Context) error {
+		if v, ok := m.verifier.(contextVerifier); ok {
+			return v.CheckSignatureContext(ctx, ms)
 		}
+		return m.verifier.CheckSignature(ms)
+	}); err != nil {
+		return OutcomeBadSignature, err
+	}
+
+	// The topic header is not signed, so the verified message is checked too.
+	if msg.Topic() != info.TopicArn {
+		return OutcomeTopicMismatch, ErrInvalidTopicArn
+	}
+	return OutcomeOK, nil
+}
+
+func (m *Middleware) afterVerify(ctx context.Context, outcome Outcome, msg Message, err error) {
+	if outcome == OutcomeBadJSON {
+		return
+	}
+	for _, h := range m.hooks {
+		if h.AfterVerify != nil {
+			h.AfterVerify(ctx, msg, err)
+		}
+	}
+}
+
+func outcomeStatus(outcome Outcome) int {
+	if outcome == OutcomeBadJSON {
+		return http.StatusBadRequest
+	}
+	return http.StatusForbidden
+}
+
+func (m *Middleware) confirm(ctx context.Context, msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+	confirm := m.confirmer.ConfirmSubscription
+	if c, ok := m.confirmer.(contextConfirmer); ok {
+		confirm = func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+			return c.ConfirmSubscriptionContext(ctx, msg)
+		}
+	}
+	if m.authenticateOnUnsubscribe {
+		c, ok := m.confirmer.(AuthenticatedConfirmer)
+		if !ok {
+			return nil, ErrAuthenticatedConfirmationUnsupported
+		}
+		confirm = c.ConfirmSubscriptionAuthenticated
+		if c, ok := c.(contextAuthenticatedConfirmer); ok {
+			confirm = func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+				return c.ConfirmSubscriptionAuthenticatedContext(ctx, msg)
+			}
+		}
+	}
+	result, err := confirm(msg)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range m.confirmed {
+		f(msg)
+	}
+	for _, h := range m.hooks {
+		if h.OnConfirm != nil {
+			h.OnConfirm(ctx, msg, result)
+		}
+	}
+	return result, nil
+}
+
+func (m *Middleware) readBody(r *http.Request) ([]byte, error) {
+	if r.ContentLength > m.maxBodySize {
+		return nil, ErrRequestBodyTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, m.maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > m.maxBodySize {
+		return nil, ErrRequestBodyTooLarge
+	}
+	return body, nil
+}
+
+func (m *Middleware) decode(body []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if m.strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return ErrTrailingData
+	}
+	return nil
+}
+
+func checkContentType(contentType string) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ErrInvalidContentType
+	}
+	if mediaType != snsContentType || !strings.EqualFold(params["charset"], snsCharset) {
+		return ErrInvalidContentType
 	}
+	return nil
 }
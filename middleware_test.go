@@ -2,25 +2,43 @@ package sns
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 )
 
-var _ subscriber = (*mockSubscriber)(nil)
+var (
+	_ Verifier               = (*mockSubscriber)(nil)
+	_ Confirmer              = (*mockSubscriber)(nil)
+	_ AuthenticatedConfirmer = (*mockSubscriber)(nil)
+)
+
+// setSubscriber makes m verify messages and confirm subscriptions with s.
+func setSubscriber(m *Middleware, s *mockSubscriber) {
+	m.verifier = s
+	m.confirmer = s
+}
 
 type mockSubscriber struct {
-	ExpectConfirmSubscription func(msg SubscriptionConfirmation) (string, error)
-	ExpectValidateCertURL     func(certURL string) error
-	ExpectCheckSignature      func(ms MessageSignature) error
+	ExpectConfirmSubscription              func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error)
+	ExpectConfirmSubscriptionAuthenticated func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error)
+	ExpectValidateCertURL                  func(certURL string) error
+	ExpectCheckSignature                   func(ms MessageSignature) error
 }
 
-func (m *mockSubscriber) ConfirmSubscription(msg SubscriptionConfirmation) (string, error) {
+func (m *mockSubscriber) ConfirmSubscription(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
 	return m.ExpectConfirmSubscription(msg)
 }
 
+func (m *mockSubscriber) ConfirmSubscriptionAuthenticated(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+	return m.ExpectConfirmSubscriptionAuthenticated(msg)
+}
+
 func (m *mockSubscriber) ValidateCertURL(certURL string) error {
 	return m.ExpectValidateCertURL(certURL)
 }
@@ -29,12 +47,34 @@ func (m *mockSubscriber) CheckSignature(ms MessageSignature) error {
 	return m.ExpectCheckSignature(ms)
 }
 
+// signedNotification returns the Notification of testdata/sign_notification,
+// signed with testdata/privatekey.pem, after applying overrides. Overriding a
+// signed field invalidates the signature.
+func signedNotification(overrides ...func(msg *Notification)) Notification {
+	msg := Notification{
+		Type:             "Notification",
+		MessageId:        "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		TopicArn:         "arn:aws:sns:us-west-2:123456789012:MyTopic",
+		Subject:          "My First Message",
+		Message:          "Hello world!",
+		Timestamp:        mustParseTimestamp("2012-05-02T00:54:06.655Z"),
+		SignatureVersion: "1",
+		Signature:        "cwMmnINV7NWn5wb4o1faQx9QZBOEpSaJaA86Asdkrpr9C0rdkI/RnyUNl5DrqmueaCiCImuy4Jh0CNeOzqXEdv6WuBjUPbQT/YyAb1h00VVqvjyOvsl2kq+7B3bTfNEahHFZJS2Xh0AtwtWENt159iNnlIRD5NSeVlRyicVv2mgCgK9qxLGGyOFESk43sqUnx5abr0mDR2oFRgbWgwHOly3bQjoaXCfrFYXbmEpz9mMScxoOcRgAUqGVkNLzNBDPU4d9OiBwHxifZBfA6AB3ZxoLm/IZXQJCoK7g44O3NjBCC5nnaMDnHJm1TeSqwVXx8MQQ+8LHhcLbghKkPvo33g==",
+		SigningCertURL:   "https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem",
+		UnsubscribeURL:   "https://sns.us-west-2.amazonaws.com/?Action=Unsubscribe&SubscriptionArn=arn:aws:sns:us-west-2:123456789012:MyTopic:c9135db0-26c4-47ec-8998-413945fb5a96",
+	}
+	for _, override := range overrides {
+		override(&msg)
+	}
+	return msg
+}
+
 func TestMiddleware_Subscribe_Notification(t *testing.T) {
 	t.Parallel()
 
 	tests := []struct {
 		name           string
-		prepare        func() subscriber
+		prepare        func() *mockSubscriber
 		topicARN       string
 		messageType    string
 		body           map[string]interface{}
@@ -42,7 +82,7 @@ func TestMiddleware_Subscribe_Notification(t *testing.T) {
 	}{
 		{
 			name: "it returns ok",
-			prepare: func() subscriber {
+			prepare: func() *mockSubscriber {
 				c := &mockSubscriber{
 					ExpectValidateCertURL: func(certURL string) error {
 						if certURL != "https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem" {
@@ -77,10 +117,10 @@ func TestMiddleware_Subscribe_Notification(t *testing.T) {
 		},
 		{
 			name: "it returns forbidden when ValidateCertURL failed",
-			prepare: func() subscriber {
+			prepare: func() *mockSubscriber {
 				c := &mockSubscriber{
-					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (string, error) {
-						return "", nil
+					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+						return &ConfirmSubscriptionResult{}, nil
 					},
 					ExpectValidateCertURL: func(certURL string) error {
 						return ErrInvalidCertURLHost
@@ -109,10 +149,10 @@ func TestMiddleware_Subscribe_Notification(t *testing.T) {
 		},
 		{
 			name: "it returns forbidden when CheckSignature failed",
-			prepare: func() subscriber {
+			prepare: func() *mockSubscriber {
 				c := &mockSubscriber{
-					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (string, error) {
-						return "", nil
+					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+						return &ConfirmSubscriptionResult{}, nil
 					},
 					ExpectValidateCertURL: func(certURL string) error {
 						return nil
@@ -157,7 +197,7 @@ func TestMiddleware_Subscribe_Notification(t *testing.T) {
 
 			w := httptest.NewRecorder()
 			m := NewMiddleware()
-			m.subscriber = tt.prepare()
+			setSubscriber(m, tt.prepare())
 			h := m.Subscribe(tt.topicARN)(handler)
 			h.ServeHTTP(w, req)
 
@@ -179,7 +219,7 @@ func TestMiddleware_Subscribe_SubscriptionConfirmation(t *testing.T) {
 		TopicArn:         "arn:aws:sns:us-west-2:123456789012:MyTopic",
 		Message:          "You have chosen to subscribe to the topic arn:aws:sns:us-west-2:123456789012:MyTopic.\nTo confirm the subscription, visit the SubscribeURL included in this message.",
 		SubscribeURL:     "https://sns.us-west-2.amazonaws.com/?Action=ConfirmSubscription&TopicArn=arn:aws:sns:us-west-2:123456789012:MyTopic&Token=Ethevee8dae4mie3",
-		Timestamp:        "2012-04-26T20:45:04.751Z",
+		Timestamp:        mustParseTimestamp("2012-04-26T20:45:04.751Z"),
 		SignatureVersion: "1",
 		Signature:        "EXAMPLEpH+DcEwjAPg8O9mY8dReBSwksfg2S7WKQcikcNKWLQjwu6A4VbeS0QHVCkhRS7fUQvi2egU3N858fiTDN6bkkOxYDVrY0Ad8L10Hs3zH81mtnPk5uvvolIC1CXGu43obcgFxeL3khZl8IKvO61GWB6jI9b5+gLPoBc1Q=",
 		SigningCertURL:   "https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem",
@@ -187,7 +227,7 @@ func TestMiddleware_Subscribe_SubscriptionConfirmation(t *testing.T) {
 
 	tests := []struct {
 		name           string
-		prepare        func() subscriber
+		prepare        func() *mockSubscriber
 		topicARN       string
 		messageType    string
 		body           map[string]interface{}
@@ -195,13 +235,13 @@ func TestMiddleware_Subscribe_SubscriptionConfirmation(t *testing.T) {
 	}{
 		{
 			name: "it returns ok",
-			prepare: func() subscriber {
+			prepare: func() *mockSubscriber {
 				c := &mockSubscriber{
-					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (string, error) {
+					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
 						if msg != wantMsg {
 							t.Error("invalid msg")
 						}
-						return "success", nil
+						return &ConfirmSubscriptionResult{}, nil
 					},
 					ExpectValidateCertURL: func(certURL string) error {
 						if certURL != wantMsg.SigningCertURL {
@@ -236,10 +276,10 @@ func TestMiddleware_Subscribe_SubscriptionConfirmation(t *testing.T) {
 		},
 		{
 			name: "it returns forbidden when ConfirmSubscription failed",
-			prepare: func() subscriber {
+			prepare: func() *mockSubscriber {
 				c := &mockSubscriber{
-					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (string, error) {
-						return "", ErrConfirmSubscription
+					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+						return nil, ErrConfirmSubscription
 					},
 					ExpectValidateCertURL: func(certURL string) error {
 						return nil
@@ -268,10 +308,10 @@ func TestMiddleware_Subscribe_SubscriptionConfirmation(t *testing.T) {
 		},
 		{
 			name: "it returns forbidden when ValidateCertURL failed",
-			prepare: func() subscriber {
+			prepare: func() *mockSubscriber {
 				c := &mockSubscriber{
-					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (string, error) {
-						return "ok", nil
+					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+						return &ConfirmSubscriptionResult{}, nil
 					},
 					ExpectValidateCertURL: func(certURL string) error {
 						return ErrInvalidCertURLSchema
@@ -300,10 +340,10 @@ func TestMiddleware_Subscribe_SubscriptionConfirmation(t *testing.T) {
 		},
 		{
 			name: "it returns forbidden when CheckSignature failed",
-			prepare: func() subscriber {
+			prepare: func() *mockSubscriber {
 				c := &mockSubscriber{
-					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (string, error) {
-						return "ok", nil
+					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+						return &ConfirmSubscriptionResult{}, nil
 					},
 					ExpectValidateCertURL: func(certURL string) error {
 						return nil
@@ -348,7 +388,7 @@ func TestMiddleware_Subscribe_SubscriptionConfirmation(t *testing.T) {
 
 			w := httptest.NewRecorder()
 			m := NewMiddleware()
-			m.subscriber = tt.prepare()
+			setSubscriber(m, tt.prepare())
 			h := m.Subscribe(tt.topicARN)(handler)
 			h.ServeHTTP(w, req)
 
@@ -359,3 +399,579 @@ func TestMiddleware_Subscribe_SubscriptionConfirmation(t *testing.T) {
 		})
 	}
 }
+
+func TestMiddleware_Subscribe_Body(t *testing.T) {
+	t.Parallel()
+
+	b, _ := json.Marshal(signedNotification())
+
+	var withUnknownField map[string]interface{}
+	json.Unmarshal(b, &withUnknownField)
+	withUnknownField["Unknown"] = "value"
+	unknown, _ := json.Marshal(withUnknownField)
+
+	tests := []struct {
+		name           string
+		opts           []Option
+		contentType    string
+		body           []byte
+		wantStatusCode int
+	}{
+		{
+			name:           "it returns ok",
+			contentType:    "text/plain; charset=UTF-8",
+			body:           b,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "it returns request entity too large when body exceeds max size",
+			opts:           []Option{WithMaxBodySize(int64(len(b) - 1))},
+			contentType:    "text/plain; charset=UTF-8",
+			body:           b,
+			wantStatusCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "it returns bad request when body has trailing data",
+			contentType:    "text/plain; charset=UTF-8",
+			body:           append(append([]byte{}, b...), []byte(`{}`)...),
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "it returns bad request when body is not json",
+			contentType:    "text/plain; charset=UTF-8",
+			body:           []byte("not json"),
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "it accepts unknown fields when not strict",
+			contentType:    "application/json",
+			body:           unknown,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "it returns bad request for unknown fields when strict",
+			opts:           []Option{WithStrict()},
+			contentType:    "text/plain; charset=UTF-8",
+			body:           unknown,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "it returns unsupported media type when strict",
+			opts:           []Option{WithStrict()},
+			contentType:    "application/json",
+			body:           b,
+			wantStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "it returns ok for sns content type when strict",
+			opts:           []Option{WithStrict()},
+			contentType:    "text/plain; charset=utf-8",
+			body:           b,
+			wantStatusCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprintf(w, "OK")
+			}
+
+			topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, "Notification")
+
+			w := httptest.NewRecorder()
+			m := NewMiddleware(tt.opts...)
+			setSubscriber(m, &mockSubscriber{
+				ExpectValidateCertURL: func(certURL string) error {
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return nil
+				},
+			})
+			h := m.Subscribe(topicARN)(handler)
+			h.ServeHTTP(w, req)
+
+			resp := w.Result()
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("Subscribe() = %v, want %v", resp.StatusCode, tt.wantStatusCode)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Subscribe_RejectBody(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	deny := func(ctx context.Context, msg SubscriptionConfirmation) (Decision, error) {
+		return DecisionDeny, nil
+	}
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		topicARN     string
+		bodyTopicARN string
+		messageType  string
+		wantBody     string
+	}{
+		{
+			name:        "topic mismatch",
+			topicARN:    "arn:aws:sns:us-west-2:123456789012:OtherTopic",
+			messageType: "Notification",
+			wantBody:    "invalid SNS TopicArn\n",
+		},
+		{
+			name:         "signed topic mismatch",
+			topicARN:     topicARN,
+			bodyTopicARN: "arn:aws:sns:us-west-2:123456789012:OtherTopic",
+			messageType:  "Notification",
+			wantBody:     "invalid SNS TopicArn\n",
+		},
+		{
+			name:         "signed topic mismatch on confirmation",
+			topicARN:     topicARN,
+			bodyTopicARN: "arn:aws:sns:us-west-2:123456789012:OtherTopic",
+			messageType:  "SubscriptionConfirmation",
+			wantBody:     "invalid SNS TopicArn\n",
+		},
+		{
+			name:        "unexpected message type",
+			topicARN:    topicARN,
+			messageType: "Unknown",
+			wantBody:    "unexpected message type\n",
+		},
+		{
+			name:        "confirmation denied",
+			opts:        []Option{WithOnSubscriptionConfirmation(deny)},
+			topicARN:    topicARN,
+			messageType: "SubscriptionConfirmation",
+			wantBody:    "subscription confirmation denied\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := NewMiddleware(tt.opts...)
+			setSubscriber(m, &mockSubscriber{
+				ExpectValidateCertURL: func(certURL string) error {
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return nil
+				},
+			})
+			bodyTopicARN := tt.bodyTopicARN
+			if bodyTopicARN == "" {
+				bodyTopicARN = tt.topicARN
+			}
+			b, _ := json.Marshal(map[string]interface{}{
+				"Type":     tt.messageType,
+				"TopicArn": bodyTopicARN,
+				"Token":    "Ethevee8dae4mie3",
+			})
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+			req.Header.Set(XAmzSnsTopicArn, tt.topicARN)
+			req.Header.Set(XAmzSnsMessageType, tt.messageType)
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("Subscribe() = %v, want %v", w.Code, http.StatusForbidden)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Subscribe_RawEnvelope(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	b, _ := json.Marshal(signedNotification())
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		raw, err := GetRawEnvelope(r)
+		if err != nil {
+			t.Errorf("err should be nil, but got %q", err)
+		}
+		if !bytes.Equal(raw, b) {
+			t.Errorf("GetRawEnvelope() = %s, want %s", raw, b)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("err should be nil, but got %q", err)
+		}
+		if !bytes.Equal(body, b) {
+			t.Errorf("r.Body = %s, want %s", body, b)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+	req.Header.Set(XAmzSnsTopicArn, topicARN)
+	req.Header.Set(XAmzSnsMessageType, "Notification")
+
+	w := httptest.NewRecorder()
+	m := NewMiddleware()
+	setSubscriber(m, &mockSubscriber{
+		ExpectValidateCertURL: func(certURL string) error {
+			return nil
+		},
+		ExpectCheckSignature: func(ms MessageSignature) error {
+			return nil
+		},
+	})
+	m.Subscribe(topicARN)(handler).ServeHTTP(w, req)
+
+	if resp := w.Result(); resp.StatusCode != http.StatusOK {
+		t.Errorf("Subscribe() = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestMiddleware_Subscribe_VerificationInfo(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	subscriptionARN := "arn:aws:sns:us-west-2:123456789012:MyTopic:c9135db0-26c4-47ec-8998-413945fb5a96"
+	b, _ := json.Marshal(signedNotification())
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		info, err := GetVerificationInfo(r)
+		if err != nil {
+			t.Errorf("err should be nil, but got %q", err)
+		}
+		if info.TopicArn != topicARN {
+			t.Errorf("TopicArn = %v, want %v", info.TopicArn, topicARN)
+		}
+		if info.SubscriptionArn != subscriptionARN {
+			t.Errorf("SubscriptionArn = %v, want %v", info.SubscriptionArn, subscriptionARN)
+		}
+		if info.MessageId != "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324" {
+			t.Errorf("MessageId = %v", info.MessageId)
+		}
+		if info.SigningCertURL != "https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem" {
+			t.Errorf("SigningCertURL = %v", info.SigningCertURL)
+		}
+		if info.SignatureVersion != "1" {
+			t.Errorf("SignatureVersion = %v, want 1", info.SignatureVersion)
+		}
+		if info.VerifiedAt.IsZero() {
+			t.Error("VerifiedAt should not be zero")
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+	req.Header.Set(XAmzSnsTopicArn, topicARN)
+	req.Header.Set(XAmzSnsMessageType, "Notification")
+	req.Header.Set(XAmzSnsMessageId, "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324")
+	req.Header.Set(XAmzSnsSubscriptionArn, subscriptionARN)
+
+	w := httptest.NewRecorder()
+	m := NewMiddleware()
+	setSubscriber(m, &mockSubscriber{
+		ExpectValidateCertURL: func(certURL string) error {
+			return nil
+		},
+		ExpectCheckSignature: func(ms MessageSignature) error {
+			return nil
+		},
+	})
+	m.Subscribe(topicARN)(handler).ServeHTTP(w, req)
+
+	if resp := w.Result(); resp.StatusCode != http.StatusOK {
+		t.Errorf("Subscribe() = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestMiddleware_Subscribe_AuthenticatedConfirmation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		opts              []Option
+		wantAuthenticated bool
+	}{
+		{
+			name:              "it confirms with the SubscribeURL by default",
+			wantAuthenticated: false,
+		},
+		{
+			name:              "it confirms with the ConfirmSubscription action",
+			opts:              []Option{WithAuthenticatedConfirmation()},
+			wantAuthenticated: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+			authenticated := false
+			m := NewMiddleware(tt.opts...)
+			setSubscriber(m, &mockSubscriber{
+				ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+					return &ConfirmSubscriptionResult{}, nil
+				},
+				ExpectConfirmSubscriptionAuthenticated: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+					authenticated = true
+					return &ConfirmSubscriptionResult{SubscriptionArn: "arn:aws:sns:us-west-2:123456789012:MyTopic:80289ba6-0fd4-4079-afb4-ce8c8260f0ca"}, nil
+				},
+				ExpectValidateCertURL: func(certURL string) error {
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return nil
+				},
+			})
+
+			b, _ := json.Marshal(map[string]interface{}{
+				"Type":     "SubscriptionConfirmation",
+				"Token":    "Ethevee8dae4mie3",
+				"TopicArn": topicARN,
+			})
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, "SubscriptionConfirmation")
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Subscribe() = %v, want %v", w.Code, http.StatusOK)
+			}
+			if authenticated != tt.wantAuthenticated {
+				t.Errorf("authenticated = %v, want %v", authenticated, tt.wantAuthenticated)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Subscribe_ConfirmSubscriptionResponse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		result         *ConfirmSubscriptionResult
+		err            error
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:           "it does not echo the response from SNS",
+			result:         &ConfirmSubscriptionResult{SubscriptionArn: "arn:aws:sns:us-west-2:123456789012:MyTopic:2bcfbf39-05c3-41de-beaa-fcfcc21c8f55"},
+			wantStatusCode: http.StatusOK,
+			wantBody:       "",
+		},
+		{
+			name:           "it does not echo the error from SNS",
+			err:            &APIError{StatusCode: http.StatusForbidden, Code: "AuthorizationError", Message: "User is not authorized"},
+			wantStatusCode: http.StatusForbidden,
+			wantBody:       ErrConfirmSubscription.Error() + "\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+			m := NewMiddleware()
+			setSubscriber(m, &mockSubscriber{
+				ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+					return tt.result, tt.err
+				},
+				ExpectValidateCertURL: func(certURL string) error {
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return nil
+				},
+			})
+
+			b, _ := json.Marshal(map[string]interface{}{
+				"Type":     "SubscriptionConfirmation",
+				"Token":    "Ethevee8dae4mie3",
+				"TopicArn": topicARN,
+			})
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, "SubscriptionConfirmation")
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("Subscribe() = %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+type verifierFunc func(ms MessageSignature) error
+
+func (f verifierFunc) ValidateCertURL(certURL string) error {
+	return nil
+}
+
+func (f verifierFunc) CheckSignature(ms MessageSignature) error {
+	return f(ms)
+}
+
+type confirmerFunc func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error)
+
+func (f confirmerFunc) ConfirmSubscription(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+	return f(msg)
+}
+
+func TestMiddleware_Subscribe_VerifierConfirmer(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	b, _ := json.Marshal(SubscriptionConfirmation{Type: "SubscriptionConfirmation", TopicArn: topicARN, Token: "Ethevee8dae4mie3"})
+
+	tests := map[string]struct {
+		opts           []Option
+		signatureErr   error
+		wantConfirmed  bool
+		wantStatusCode int
+	}{
+		"success": {
+			wantConfirmed:  true,
+			wantStatusCode: http.StatusOK,
+		},
+		"CheckSignature failed": {
+			signatureErr:   ErrInvalidSignature,
+			wantConfirmed:  false,
+			wantStatusCode: http.StatusForbidden,
+		},
+		"AuthenticatedConfirmer not implemented": {
+			opts:           []Option{WithAuthenticatedConfirmation()},
+			wantConfirmed:  false,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			confirmed := false
+			opts := append([]Option{
+				WithVerifier(verifierFunc(func(ms MessageSignature) error {
+					return tt.signatureErr
+				})),
+				WithConfirmer(confirmerFunc(func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+					confirmed = true
+					return &ConfirmSubscriptionResult{}, nil
+				})),
+			}, tt.opts...)
+			m := NewMiddleware(opts...)
+
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, "SubscriptionConfirmation")
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("Subscribe() = %v, want %v", w.Code, tt.wantStatusCode)
+			}
+			if confirmed != tt.wantConfirmed {
+				t.Errorf("confirmed = %v, want %v", confirmed, tt.wantConfirmed)
+			}
+		})
+	}
+}
+
+func TestMiddleware_Subscribe_UnsubscribeConfirmation(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	b, _ := json.Marshal(map[string]interface{}{
+		"Type":      "UnsubscribeConfirmation",
+		"MessageId": "47138184-6831-46b8-8f7c-afc488602d7d",
+		"Token":     "Ethevee8dae4mie3",
+		"TopicArn":  topicARN,
+		"Timestamp": "2012-04-26T20:06:41.581Z",
+	})
+
+	tests := []struct {
+		name           string
+		checkSignature error
+		handleErr      error
+		wantCalled     bool
+		wantStatusCode int
+	}{
+		{
+			name:           "it returns ok",
+			wantCalled:     true,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "it returns internal server error when the callback failed",
+			handleErr:      errors.New("failed"),
+			wantCalled:     true,
+			wantStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:           "it returns forbidden when CheckSignature failed",
+			checkSignature: ErrInvalidSignature,
+			wantCalled:     false,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			called := false
+			m := NewMiddleware(WithOnUnsubscribeConfirmation(func(ctx context.Context, msg UnsubscribeConfirmation) error {
+				called = true
+				if msg.Token != "Ethevee8dae4mie3" || msg.SentAt().IsZero() {
+					t.Errorf("msg = %+v", msg)
+				}
+				return tt.handleErr
+			}))
+			setSubscriber(m, &mockSubscriber{
+				ExpectValidateCertURL: func(certURL string) error {
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return tt.checkSignature
+				},
+			})
+
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, "UnsubscribeConfirmation")
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("next should not be called")
+			}).ServeHTTP(w, req)
+
+			if called != tt.wantCalled {
+				t.Errorf("called = %v, want %v", called, tt.wantCalled)
+			}
+			if w.Code != tt.wantStatusCode {
+				t.Errorf("Subscribe() = %v, want %v", w.Code, tt.wantStatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package sns
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPoolWorkers   = 4
+	DefaultPoolQueueSize = 100
+)
+
+var ErrQueueFull = errors.New("error queue full")
+
+// Pool is a NotificationHandler that queues notifications and handles them in
+// background workers, so that the SNS delivery request is answered before the
+// 15-second delivery timeout. Dispatch responds with 503 when the queue is full
+// so that SNS retries the delivery.
+type Pool struct {
+	handler    NotificationHandler
+	workers    int
+	queueSize  int
+	onComplete []func(ctx context.Context, msg Notification, err error)
+
+	queue chan poolJob
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type poolJob struct {
+	ctx context.Context
+	msg Notification
+}
+
+type PoolOption func(*Pool)
+
+// WithPool makes the middleware pass verified Notifications to p instead of the
+// next handler, and respond with 200 as soon as they are queued, or with 503
+// when the queue is full or p is shut down.
+func WithPool(p *Pool) Option {
+	return func(m *Middleware) {
+		m.pool = p
+	}
+}
+
+// WithPoolWorkers sets the number of workers. Values below 1 start one worker.
+func WithPoolWorkers(n int) PoolOption {
+	return func(p *Pool) {
+		p.workers = n
+	}
+}
+
+// WithPoolQueueSize sets how many notifications can wait for a worker. A
+// negative size is treated as zero, so that notifications are only accepted
+// while a worker is idle.
+func WithPoolQueueSize(n int) PoolOption {
+	return func(p *Pool) {
+		p.queueSize = n
+	}
+}
+
+// WithOnNotificationComplete is called after each notification with the error
+// returned by the handler.
+func WithOnNotificationComplete(f func(ctx context.Context, msg Notification, err error)) PoolOption {
+	return func(p *Pool) {
+		p.onComplete = append(p.onComplete, f)
+	}
+}
+
+// NewPool starts the workers handling notifications with h.
+func NewPool(h NotificationHandler, opts ...PoolOption) *Pool {
+	p := &Pool{
+		handler:   h,
+		workers:   DefaultPoolWorkers,
+		queueSize: DefaultPoolQueueSize,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.workers < 1 {
+		p.workers = 1
+	}
+	if p.queueSize < 0 {
+		p.queueSize = 0
+	}
+
+	p.queue = make(chan poolJob, p.queueSize)
+	p.wg.Add(p.workers)
+	for i := 0; i < p.workers; i++ {
+		go p.run()
+	}
+	return p
+}
+
+// HandleNotification queues msg. The context passed to the handler keeps the
+// values of ctx but not its cancellation.
+func (p *Pool) HandleNotification(ctx context.Context, msg Notification) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrShutdown
+	}
+	select {
+	case p.queue <- poolJob{ctx: detach(ctx), msg: msg}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Shutdown stops accepting notifications and waits until the queued ones are
+// handled or ctx is done.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) run() {
+	defer p.wg.Done()
+
+	for job := range p.queue {
+		err := p.handler.HandleNotification(job.ctx, job.msg)
+		for _, f := range p.onComplete {
+			f(job.ctx, job.msg, err)
+		}
+	}
+}
+
+// detachedContext keeps the values of its parent after the request is done.
+type detachedContext struct {
+	parent context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
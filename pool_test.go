@@ -0,0 +1,216 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMiddleware_Dispatch_Pool(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	b, _ := json.Marshal(Notification{
+		Type:      "Notification",
+		MessageId: "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		TopicArn:  topicARN,
+		Message:   "Hello world!",
+	})
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	h := NotificationHandlerFunc(func(ctx context.Context, msg Notification) error {
+		started <- struct{}{}
+		<-release
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, ok := FromContext(ctx); !ok {
+			return ErrNotFoundNotification
+		}
+		return errors.New("failed")
+	})
+
+	var (
+		mu       sync.Mutex
+		complete []error
+	)
+	pool := NewPool(h,
+		WithPoolWorkers(1),
+		WithPoolQueueSize(1),
+		WithOnNotificationComplete(func(ctx context.Context, msg Notification, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			complete = append(complete, err)
+		}),
+	)
+
+	m := NewMiddleware()
+	setSubscriber(m, &mockSubscriber{
+		ExpectValidateCertURL: func(certURL string) error {
+			return nil
+		},
+		ExpectCheckSignature: func(ms MessageSignature) error {
+			return nil
+		},
+	})
+	handler := m.Dispatch(topicARN, pool)
+
+	dispatch := func() int {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(b)).WithContext(ctx)
+		req.Header.Set(XAmzSnsTopicArn, topicARN)
+		req.Header.Set(XAmzSnsMessageType, "Notification")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// The first notification is taken by the worker, the second waits in the
+	// queue and the third does not fit.
+	if code := dispatch(); code != http.StatusOK {
+		t.Errorf("Dispatch() = %v, want %v", code, http.StatusOK)
+	}
+	<-started
+	if code := dispatch(); code != http.StatusOK {
+		t.Errorf("Dispatch() = %v, want %v", code, http.StatusOK)
+	}
+	if code := dispatch(); code != http.StatusServiceUnavailable {
+		t.Errorf("Dispatch() = %v, want %v", code, http.StatusServiceUnavailable)
+	}
+
+	close(release)
+	go func() {
+		for range started {
+		}
+	}()
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	close(started)
+
+	if code := dispatch(); code != http.StatusServiceUnavailable {
+		t.Errorf("Dispatch() = %v, want %v", code, http.StatusServiceUnavailable)
+	}
+	if len(complete) != 2 {
+		t.Fatalf("complete = %v, want 2 notifications", complete)
+	}
+	for _, err := range complete {
+		if err == nil || err.Error() != "failed" {
+			t.Errorf("complete err = %v, want %v", err, "failed")
+		}
+	}
+}
+
+func TestMiddleware_Subscribe_WithPool(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	b, _ := json.Marshal(Notification{Type: "Notification", MessageId: "1", TopicArn: topicARN})
+
+	handled := make(chan Notification, 1)
+	release := make(chan struct{})
+	pool := NewPool(NotificationHandlerFunc(func(ctx context.Context, msg Notification) error {
+		<-release
+		handled <- msg
+		return nil
+	}), WithPoolWorkers(1), WithPoolQueueSize(0))
+
+	m := NewMiddleware(WithPool(pool))
+	setSubscriber(m, &mockSubscriber{
+		ExpectValidateCertURL: func(certURL string) error {
+			return nil
+		},
+		ExpectCheckSignature: func(ms MessageSignature) error {
+			return nil
+		},
+	})
+	subscribe := func() int {
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+		req.Header.Set(XAmzSnsTopicArn, topicARN)
+		req.Header.Set(XAmzSnsMessageType, "Notification")
+		w := httptest.NewRecorder()
+		m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("next should not be called")
+		}).ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// The worker may not be waiting yet, so retry until it takes the notification.
+	code := subscribe()
+	for i := 0; code == http.StatusServiceUnavailable && i < 100; i++ {
+		time.Sleep(time.Millisecond)
+		code = subscribe()
+	}
+	if code != http.StatusOK {
+		t.Errorf("Subscribe() = %v, want %v", code, http.StatusOK)
+	}
+	if code := subscribe(); code != http.StatusServiceUnavailable {
+		t.Errorf("Subscribe() = %v, want %v while the worker is busy", code, http.StatusServiceUnavailable)
+	}
+
+	close(release)
+	if msg := <-handled; msg.MessageId != "1" {
+		t.Errorf("handled = %v, want 1", msg.MessageId)
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewPool_InvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []PoolOption
+	}{
+		{
+			name: "no workers",
+			opts: []PoolOption{WithPoolWorkers(0)},
+		},
+		{
+			name: "negative workers",
+			opts: []PoolOption{WithPoolWorkers(-1)},
+		},
+		{
+			name: "negative queue size",
+			opts: []PoolOption{WithPoolQueueSize(-1)},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handled := make(chan struct{})
+			p := NewPool(NotificationHandlerFunc(func(ctx context.Context, msg Notification) error {
+				close(handled)
+				return nil
+			}), tt.opts...)
+
+			var err error
+			for i := 0; i < 100; i++ {
+				if err = p.HandleNotification(context.Background(), Notification{}); err != ErrQueueFull {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if err != nil {
+				t.Fatalf("HandleNotification() error = %v", err)
+			}
+			<-handled
+			if err := p.Shutdown(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
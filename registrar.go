@@ -0,0 +1,275 @@
+package sns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const attributePendingConfirmation = "PendingConfirmation"
+
+var ErrRegistrarShutdown = errors.New("error registrar shut down")
+
+type SubscriptionState int
+
+const (
+	SubscriptionStateUnsubscribed SubscriptionState = iota + 1
+	SubscriptionStatePending
+	SubscriptionStateConfirmed
+	SubscriptionStateFailed
+)
+
+var subscriptionStateStrings = map[SubscriptionState]string{
+	SubscriptionStateUnsubscribed: "Unsubscribed",
+	SubscriptionStatePending:      "Pending",
+	SubscriptionStateConfirmed:    "Confirmed",
+	SubscriptionStateFailed:       "Failed",
+}
+
+func (s SubscriptionState) String() string {
+	return subscriptionStateStrings[s]
+}
+
+type subscriptionManager interface {
+	Subscribe(ctx context.Context, topicArn, protocol, endpoint string, attributes map[string]string) (string, error)
+	Unsubscribe(ctx context.Context, subscriptionArn string) error
+	GetSubscriptionAttributes(ctx context.Context, subscriptionArn string) (map[string]string, error)
+}
+
+type registration struct {
+	state           SubscriptionState
+	subscriptionArn string
+}
+
+// Registrar subscribes the service's own endpoint to topics on startup and
+// reports ready once every subscription has been confirmed.
+type Registrar struct {
+	manager               subscriptionManager
+	endpoint              string
+	topicArns             []string
+	attributes            map[string]string
+	unsubscribeOnShutdown bool
+
+	mu            sync.Mutex
+	registrations map[string]*registration
+	ready         chan struct{}
+	done          chan struct{}
+	shutdown      bool
+}
+
+type RegistrarOption func(*Registrar)
+
+// WithSubscriptionAttributes sets the attributes passed to Subscribe.
+func WithSubscriptionAttributes(attributes map[string]string) RegistrarOption {
+	return func(r *Registrar) {
+		r.attributes = attributes
+	}
+}
+
+// WithUnsubscribeOnShutdown unsubscribes the endpoint from every topic on Shutdown.
+func WithUnsubscribeOnShutdown() RegistrarOption {
+	return func(r *Registrar) {
+		r.unsubscribeOnShutdown = true
+	}
+}
+
+func NewRegistrar(manager *Manager, endpoint string, topicArns []string, opts ...RegistrarOption) *Registrar {
+	r := &Registrar{
+		manager:       manager,
+		endpoint:      endpoint,
+		topicArns:     topicArns,
+		registrations: make(map[string]*registration, len(topicArns)),
+		ready:         make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, arn := range topicArns {
+		r.registrations[arn] = &registration{state: SubscriptionStateUnsubscribed}
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if len(topicArns) == 0 {
+		close(r.ready)
+	}
+	return r
+}
+
+// Start subscribes the endpoint to every topic. The SubscriptionConfirmation
+// is handled by a Middleware configured with WithRegistrar.
+func (r *Registrar) Start(ctx context.Context) error {
+	protocol := "https"
+	if strings.HasPrefix(r.endpoint, "http://") {
+		protocol = "http"
+	}
+
+	for _, topicArn := range r.topicArns {
+		r.transition(topicArn, "", SubscriptionStatePending)
+
+		subscriptionArn, err := r.manager.Subscribe(ctx, topicArn, protocol, r.endpoint, r.attributes)
+		if err != nil {
+			r.transition(topicArn, "", SubscriptionStateFailed)
+			return err
+		}
+
+		// Subscribing an endpoint that is already confirmed does not send a new
+		// SubscriptionConfirmation.
+		attributes, err := r.manager.GetSubscriptionAttributes(ctx, subscriptionArn)
+		if err != nil {
+			r.transition(topicArn, subscriptionArn, SubscriptionStateFailed)
+			return err
+		}
+		if attributes[attributePendingConfirmation] == "false" {
+			r.transition(topicArn, subscriptionArn, SubscriptionStateConfirmed)
+		} else {
+			r.transition(topicArn, subscriptionArn, SubscriptionStatePending)
+		}
+	}
+	return nil
+}
+
+// Wait blocks until every subscription has been confirmed or ctx is done. It
+// returns ErrRegistrarShutdown once Shutdown has been called.
+func (r *Registrar) Wait(ctx context.Context) error {
+	select {
+	case <-r.done:
+		return ErrRegistrarShutdown
+	default:
+	}
+
+	select {
+	case <-r.ready:
+		return nil
+	case <-r.done:
+		return ErrRegistrarShutdown
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Ready reports whether every subscription is confirmed. It is false after
+// Shutdown.
+func (r *Registrar) Ready() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.readyLocked()
+}
+
+func (r *Registrar) readyLocked() bool {
+	if r.shutdown {
+		return false
+	}
+	for _, reg := range r.registrations {
+		if reg.state != SubscriptionStateConfirmed {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Registrar) State(topicArn string) SubscriptionState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if reg, ok := r.registrations[topicArn]; ok {
+		return reg.state
+	}
+	return SubscriptionStateUnsubscribed
+}
+
+// HealthHandler responds with 200 once every subscription has been confirmed
+// and 503 until then and after Shutdown.
+func (r *Registrar) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		topicArns := make([]string, 0, len(r.registrations))
+		states := make(map[string]SubscriptionState, len(r.registrations))
+		for arn, reg := range r.registrations {
+			topicArns = append(topicArns, arn)
+			states[arn] = reg.state
+		}
+		ready := r.readyLocked()
+		r.mu.Unlock()
+		sort.Strings(topicArns)
+
+		if ready {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		for _, arn := range topicArns {
+			fmt.Fprintf(w, "%s %s\n", arn, states[arn])
+		}
+	})
+}
+
+// Shutdown marks the Registrar not ready and unsubscribes the endpoint when
+// WithUnsubscribeOnShutdown is set.
+func (r *Registrar) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.shutdown {
+		r.shutdown = true
+		close(r.done)
+	}
+	if !r.unsubscribeOnShutdown {
+		r.mu.Unlock()
+		return nil
+	}
+
+	subscriptionArns := map[string]string{}
+	for arn, reg := range r.registrations {
+		if reg.subscriptionArn != "" {
+			subscriptionArns[arn] = reg.subscriptionArn
+		}
+	}
+	r.mu.Unlock()
+
+	for topicArn, subscriptionArn := range subscriptionArns {
+		if err := r.manager.Unsubscribe(ctx, subscriptionArn); err != nil {
+			return err
+		}
+		r.transition(topicArn, "", SubscriptionStateUnsubscribed)
+	}
+	return nil
+}
+
+// confirmed is called by the middleware after it confirms a subscription.
+func (r *Registrar) confirmed(msg SubscriptionConfirmation) {
+	r.transition(msg.TopicArn, "", SubscriptionStateConfirmed)
+}
+
+// transition moves the subscription for topicArn to state. A confirmation may
+// arrive before Subscribe returns, so a confirmed subscription is never moved
+// back to pending.
+func (r *Registrar) transition(topicArn, subscriptionArn string, state SubscriptionState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reg, ok := r.registrations[topicArn]
+	if !ok {
+		return
+	}
+	if subscriptionArn != "" {
+		reg.subscriptionArn = subscriptionArn
+	}
+	if state == SubscriptionStatePending && reg.state == SubscriptionStateConfirmed {
+		return
+	}
+	if state == SubscriptionStateUnsubscribed {
+		reg.subscriptionArn = ""
+	}
+	reg.state = state
+
+	select {
+	case <-r.ready:
+		return
+	default:
+	}
+	if r.readyLocked() {
+		close(r.ready)
+	}
+}
@@ -0,0 +1,255 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+var _ subscriptionManager = (*mockSubscriptionManager)(nil)
+
+type mockSubscriptionManager struct {
+	ExpectSubscribe                 func(topicArn, protocol, endpoint string) (string, error)
+	ExpectGetSubscriptionAttributes func(subscriptionArn string) (map[string]string, error)
+
+	mu           sync.Mutex
+	unsubscribed []string
+}
+
+func (m *mockSubscriptionManager) Subscribe(ctx context.Context, topicArn, protocol, endpoint string, attributes map[string]string) (string, error) {
+	return m.ExpectSubscribe(topicArn, protocol, endpoint)
+}
+
+func (m *mockSubscriptionManager) Unsubscribe(ctx context.Context, subscriptionArn string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unsubscribed = append(m.unsubscribed, subscriptionArn)
+	return nil
+}
+
+func (m *mockSubscriptionManager) GetSubscriptionAttributes(ctx context.Context, subscriptionArn string) (map[string]string, error) {
+	return m.ExpectGetSubscriptionAttributes(subscriptionArn)
+}
+
+func TestRegistrar(t *testing.T) {
+	t.Parallel()
+
+	topicArn := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	subscriptionArn := "arn:aws:sns:us-west-2:123456789012:MyTopic:c9135db0-26c4-47ec-8998-413945fb5a96"
+
+	tests := map[string]struct {
+		prepare          func(r *Registrar) *mockSubscriptionManager
+		confirm          bool
+		wantErr          bool
+		wantState        SubscriptionState
+		wantReady        bool
+		wantUnsubscribed []string
+	}{
+		"it is ready after the confirmation is received": {
+			prepare: func(r *Registrar) *mockSubscriptionManager {
+				return &mockSubscriptionManager{
+					ExpectSubscribe: func(arn, protocol, endpoint string) (string, error) {
+						if protocol != "https" || endpoint != "https://example.com/sns" {
+							t.Errorf("Subscribe(%v, %v)", protocol, endpoint)
+						}
+						return subscriptionArn, nil
+					},
+					ExpectGetSubscriptionAttributes: func(arn string) (map[string]string, error) {
+						return map[string]string{"PendingConfirmation": "true"}, nil
+					},
+				}
+			},
+			confirm:          true,
+			wantState:        SubscriptionStateConfirmed,
+			wantReady:        true,
+			wantUnsubscribed: []string{subscriptionArn},
+		},
+		"it is not ready until the confirmation is received": {
+			prepare: func(r *Registrar) *mockSubscriptionManager {
+				return &mockSubscriptionManager{
+					ExpectSubscribe: func(arn, protocol, endpoint string) (string, error) {
+						return subscriptionArn, nil
+					},
+					ExpectGetSubscriptionAttributes: func(arn string) (map[string]string, error) {
+						return map[string]string{"PendingConfirmation": "true"}, nil
+					},
+				}
+			},
+			confirm:          false,
+			wantState:        SubscriptionStatePending,
+			wantReady:        false,
+			wantUnsubscribed: []string{subscriptionArn},
+		},
+		"it is ready when the subscription is already confirmed": {
+			prepare: func(r *Registrar) *mockSubscriptionManager {
+				return &mockSubscriptionManager{
+					ExpectSubscribe: func(arn, protocol, endpoint string) (string, error) {
+						return subscriptionArn, nil
+					},
+					ExpectGetSubscriptionAttributes: func(arn string) (map[string]string, error) {
+						return map[string]string{"PendingConfirmation": "false"}, nil
+					},
+				}
+			},
+			confirm:          false,
+			wantState:        SubscriptionStateConfirmed,
+			wantReady:        true,
+			wantUnsubscribed: []string{subscriptionArn},
+		},
+		"it stays confirmed when the confirmation arrives before Subscribe returns": {
+			prepare: func(r *Registrar) *mockSubscriptionManager {
+				return &mockSubscriptionManager{
+					ExpectSubscribe: func(arn, protocol, endpoint string) (string, error) {
+						r.confirmed(SubscriptionConfirmation{TopicArn: arn})
+						return subscriptionArn, nil
+					},
+					ExpectGetSubscriptionAttributes: func(arn string) (map[string]string, error) {
+						return map[string]string{"PendingConfirmation": "true"}, nil
+					},
+				}
+			},
+			confirm:          false,
+			wantState:        SubscriptionStateConfirmed,
+			wantReady:        true,
+			wantUnsubscribed: []string{subscriptionArn},
+		},
+		"it fails when Subscribe failed": {
+			prepare: func(r *Registrar) *mockSubscriptionManager {
+				return &mockSubscriptionManager{
+					ExpectSubscribe: func(arn, protocol, endpoint string) (string, error) {
+						return "", &APIError{StatusCode: http.StatusForbidden, Code: "AuthorizationError"}
+					},
+				}
+			},
+			wantErr:   true,
+			wantState: SubscriptionStateFailed,
+			wantReady: false,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := NewRegistrar(nil, "https://example.com/sns", []string{topicArn}, WithUnsubscribeOnShutdown())
+			manager := tt.prepare(r)
+			r.manager = manager
+
+			if err := r.Start(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("Start() = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.confirm {
+				m := NewMiddleware(WithRegistrar(r))
+				setSubscriber(m, &mockSubscriber{
+					ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+						return &ConfirmSubscriptionResult{}, nil
+					},
+					ExpectValidateCertURL: func(certURL string) error {
+						return nil
+					},
+					ExpectCheckSignature: func(ms MessageSignature) error {
+						return nil
+					},
+				})
+				b, _ := json.Marshal(map[string]interface{}{
+					"Type":     "SubscriptionConfirmation",
+					"TopicArn": topicArn,
+				})
+				req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+				req.Header.Set(XAmzSnsTopicArn, topicArn)
+				req.Header.Set(XAmzSnsMessageType, "SubscriptionConfirmation")
+				m.Subscribe(topicArn)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			if got := r.State(topicArn); got != tt.wantState {
+				t.Errorf("State() = %v, want %v", got, tt.wantState)
+			}
+			if got := r.Ready(); got != tt.wantReady {
+				t.Errorf("Ready() = %v, want %v", got, tt.wantReady)
+			}
+
+			w := httptest.NewRecorder()
+			r.HealthHandler().ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+			wantStatusCode := http.StatusServiceUnavailable
+			if tt.wantReady {
+				wantStatusCode = http.StatusOK
+			}
+			if w.Code != wantStatusCode {
+				t.Errorf("HealthHandler() = %v, want %v", w.Code, wantStatusCode)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if err := r.Wait(ctx); (err == nil) != tt.wantReady {
+				t.Errorf("Wait() = %v, wantReady %v", err, tt.wantReady)
+			}
+
+			if err := r.Shutdown(context.Background()); err != nil {
+				t.Errorf("err should be nil, but got %q", err)
+			}
+			if !reflect.DeepEqual(manager.unsubscribed, tt.wantUnsubscribed) {
+				t.Errorf("unsubscribed = %v, want %v", manager.unsubscribed, tt.wantUnsubscribed)
+			}
+			if len(tt.wantUnsubscribed) > 0 && r.State(topicArn) != SubscriptionStateUnsubscribed {
+				t.Errorf("State() = %v, want %v", r.State(topicArn), SubscriptionStateUnsubscribed)
+			}
+			if r.Ready() {
+				t.Error("Ready() should be false after Shutdown")
+			}
+			w = httptest.NewRecorder()
+			r.HealthHandler().ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+			if w.Code != http.StatusServiceUnavailable {
+				t.Errorf("HealthHandler() = %v after Shutdown, want %v", w.Code, http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
+
+func TestRegistrar_Wait(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistrar(nil, "https://example.com/sns", []string{"arn:aws:sns:us-west-2:123456789012:MyTopic"})
+	go r.confirmed(SubscriptionConfirmation{TopicArn: "arn:aws:sns:us-west-2:123456789012:MyTopic"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.Wait(ctx); err != nil {
+		t.Errorf("Wait() = %v, want nil", err)
+	}
+	if !r.Ready() {
+		t.Error("Ready() should be true")
+	}
+}
+
+func TestRegistrar_Wait_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistrar(nil, "https://example.com/sns", []string{"arn:aws:sns:us-west-2:123456789012:MyTopic"})
+	waited := make(chan error, 1)
+	go func() {
+		waited <- r.Wait(context.Background())
+	}()
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-waited:
+		if !errors.Is(err, ErrRegistrarShutdown) {
+			t.Errorf("Wait() = %v, want %v", err, ErrRegistrarShutdown)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait() did not return after Shutdown")
+	}
+	if err := r.Wait(context.Background()); !errors.Is(err, ErrRegistrarShutdown) {
+		t.Errorf("Wait() = %v, want %v", err, ErrRegistrarShutdown)
+	}
+}
@@ -0,0 +1,159 @@
+package sns
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	OpConfirmSubscription = "ConfirmSubscription"
+	OpFetchCert           = "FetchCert"
+	OpAPI                 = "API"
+	OpHandleNotification  = "HandleNotification"
+)
+
+// RetryEvent describes a failed attempt that is about to be retried.
+type RetryEvent struct {
+	Op         string
+	Attempt    int
+	Delay      time.Duration
+	StatusCode int
+	Err        error
+}
+
+// RetryPolicy retries outbound calls with jittered exponential backoff.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Retryable reports whether the result of an attempt should be retried.
+	Retryable func(resp *http.Response, err error) bool
+	// OnRetry is called before waiting for the next attempt.
+	OnRetry func(e RetryEvent)
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+		Retryable:   DefaultRetryable,
+	}
+}
+
+// NoRetry makes a single attempt.
+func NoRetry() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// DefaultRetryable retries network errors, 429 Too Many Requests and 5xx responses.
+func DefaultRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// retryableError reports whether an error returned by a Client method should be
+// retried, classifying an *APIError by its status code as do would.
+func (p RetryPolicy) retryableError(err error) bool {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return retryable(&http.Response{StatusCode: apiErr.StatusCode, Header: http.Header{}, Body: http.NoBody}, nil)
+	case errors.Is(err, ErrMissingCredentials), errors.Is(err, ErrAuthenticatedConfirmationUnsupported):
+		return false
+	}
+	return retryable(nil, err)
+}
+
+var (
+	jitterMu sync.Mutex
+	jitter   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// backoff returns a random delay between zero and BaseDelay*2^attempt, capped at MaxDelay.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << uint(attempt)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return time.Duration(jitter.Int63n(int64(d) + 1))
+}
+
+type noRetryKey struct{}
+
+// withoutRetry makes RetryPolicy.do called with ctx attempt once, for callers
+// that retry themselves.
+func withoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// do sends the request built by newRequest until it succeeds, the result is not
+// retryable, or MaxAttempts is reached. The last response is returned to the
+// caller, which is responsible for closing its body.
+func (p RetryPolicy) do(ctx context.Context, op string, hc *http.Client, newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+	maxAttempts := p.MaxAttempts
+	if ctx.Value(noRetryKey{}) != nil {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := hc.Do(req)
+		if attempt >= maxAttempts || !retryable(resp, err) {
+			return resp, err
+		}
+
+		e := RetryEvent{
+			Op:      op,
+			Attempt: attempt,
+			Delay:   p.backoff(attempt - 1),
+			Err:     err,
+		}
+		if resp != nil {
+			e.StatusCode = resp.StatusCode
+			resp.Body.Close()
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(e)
+		}
+
+		t := time.NewTimer(e.Delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func getRequest(url string) func(ctx context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}
+}
@@ -0,0 +1,185 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_do(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		statuses     []int
+		maxAttempts  int
+		wantStatus   int
+		wantAttempts int32
+		wantRetries  int
+	}{
+		"success": {
+			statuses:     []int{http.StatusOK},
+			maxAttempts:  3,
+			wantStatus:   http.StatusOK,
+			wantAttempts: 1,
+		},
+		"retry_then_success": {
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			maxAttempts:  3,
+			wantStatus:   http.StatusOK,
+			wantAttempts: 3,
+			wantRetries:  2,
+		},
+		"exhausted": {
+			statuses:     []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			maxAttempts:  2,
+			wantStatus:   http.StatusInternalServerError,
+			wantAttempts: 2,
+			wantRetries:  1,
+		},
+		"not_retryable": {
+			statuses:     []int{http.StatusNotFound, http.StatusOK},
+			maxAttempts:  3,
+			wantStatus:   http.StatusNotFound,
+			wantAttempts: 1,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var attempts int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&attempts, 1)
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer ts.Close()
+
+			var events []RetryEvent
+			p := RetryPolicy{
+				MaxAttempts: tt.maxAttempts,
+				BaseDelay:   time.Millisecond,
+				MaxDelay:    5 * time.Millisecond,
+				OnRetry: func(e RetryEvent) {
+					events = append(events, e)
+				},
+			}
+			resp, err := p.do(context.Background(), OpFetchCert, ts.Client(), getRequest(ts.URL))
+			if err != nil {
+				t.Fatalf("do() error = %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := atomic.LoadInt32(&attempts); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			if len(events) != tt.wantRetries {
+				t.Fatalf("OnRetry called %d times, want %d", len(events), tt.wantRetries)
+			}
+			for i, e := range events {
+				if e.Op != OpFetchCert || e.Attempt != i+1 || e.StatusCode != tt.statuses[i] {
+					t.Errorf("RetryEvent = %+v", e)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_do_ContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Hour,
+		MaxDelay:    time.Hour,
+		OnRetry: func(e RetryEvent) {
+			cancel()
+		},
+	}
+	if _, err := p.do(ctx, OpAPI, ts.Client(), getRequest(ts.URL)); err != context.Canceled {
+		t.Errorf("do() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	t.Parallel()
+
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	} {
+		if d := p.backoff(attempt); d < 0 || d > max {
+			t.Errorf("backoff(%d) = %v, want [0, %v]", attempt, d, max)
+		}
+	}
+}
+
+func TestMiddleware_Subscribe_ConfirmCanceled(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewClient(WithHTTPClient(ts.Client()), WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Hour,
+		MaxDelay:    time.Hour,
+		OnRetry: func(e RetryEvent) {
+			cancel()
+		},
+	}))
+	m := NewMiddleware(WithClient(c))
+	setSubscriber(m, &mockSubscriber{
+		ExpectValidateCertURL: func(certURL string) error {
+			return nil
+		},
+		ExpectCheckSignature: func(ms MessageSignature) error {
+			return nil
+		},
+	})
+	m.confirmer = c
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	b, _ := json.Marshal(SubscriptionConfirmation{Type: "SubscriptionConfirmation", TopicArn: topicARN, SubscribeURL: ts.URL})
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(b)).WithContext(ctx)
+	req.Header.Set(XAmzSnsTopicArn, topicARN)
+	req.Header.Set(XAmzSnsMessageType, "SubscriptionConfirmation")
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Subscribe() did not return after the request was canceled")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Subscribe() = %v, want %v", w.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,59 @@
+package sns
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_signer_sign(t *testing.T) {
+	t.Parallel()
+
+	// Example from the AWS Signature Version 4 documentation.
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		contentType string
+		service     string
+		want        string
+	}{
+		{
+			name:        "iam ListUsers",
+			method:      "GET",
+			url:         "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			contentType: "application/x-www-form-urlencoded; charset=utf-8",
+			service:     "iam",
+			want:        "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("Content-Type", tt.contentType)
+
+			s := newSigner("us-east-1", Credentials{
+				AccessKeyID:     "AKIDEXAMPLE",
+				SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			})
+			s.service = tt.service
+			s.now = func() time.Time {
+				return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+			}
+			s.sign(r, nil)
+
+			if got := r.Header.Get(headerAuthorization); got != tt.want {
+				t.Errorf("Authorization = %v, want %v", got, tt.want)
+			}
+			if got := r.Header.Get(headerAmzDate); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %v, want %v", got, "20150830T123600Z")
+			}
+		})
+	}
+}
@@ -1,13 +1,17 @@
 package sns
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
+	"encoding/xml"
 	"io"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sync"
+	"time"
 )
 
 var (
@@ -15,31 +19,124 @@ var (
 	signingCertURLSchema  = "https"
 	signatureVersion      = "1"
 	signatureAlgorithm    = x509.SHA1WithRSA
+
+	maxCachedCerts = 64
 )
 
-type Client struct{}
+type Client struct {
+	httpClient  *http.Client
+	retry       RetryPolicy
+	credentials *Credentials
+	apiEndpoint string
+	metrics     Metrics
+	tracer      Tracer
+	certs       *certCache
+}
+
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the http.Client used to confirm subscriptions and fetch signing certs.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithRetryPolicy sets the policy used to retry confirmations, cert downloads
+// and API calls. DefaultRetryPolicy is used unless set.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// WithCredentials sets the credentials used by ConfirmSubscriptionAuthenticated.
+func WithCredentials(credentials Credentials) ClientOption {
+	return func(c *Client) {
+		c.credentials = &credentials
+	}
+}
 
-func NewClient() *Client {
-	return &Client{}
+// WithAPIEndpoint overrides the SNS API endpoint used by ConfirmSubscriptionAuthenticated.
+func WithAPIEndpoint(endpoint string) ClientOption {
+	return func(c *Client) {
+		c.apiEndpoint = endpoint
+	}
 }
 
-func (c *Client) ConfirmSubscription(msg SubscriptionConfirmation) (string, error) {
-	resp, err := http.Get(msg.SubscribeURL)
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy(),
+		metrics:    nopMetrics{},
+		tracer:     nopTracer{},
+		certs:      &certCache{certs: map[string]*x509.Certificate{}},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ConfirmSubscription visits the SubscribeURL and parses the
+// ConfirmSubscriptionResponse. An ErrorResponse from SNS is returned as *APIError.
+func (c *Client) ConfirmSubscription(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+	return c.ConfirmSubscriptionContext(context.Background(), msg)
+}
+
+// ConfirmSubscriptionContext is ConfirmSubscription with a context that stops
+// the request and its retries.
+func (c *Client) ConfirmSubscriptionContext(ctx context.Context, msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+	resp, err := c.retry.do(ctx, OpConfirmSubscription, c.httpClient, getRequest(msg.SubscribeURL))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", ErrConfirmSubscription
+		if apiErr, ok := decodeAPIError(resp.StatusCode, body); ok {
+			return nil, apiErr
+		}
+		return nil, ErrConfirmSubscription
+	}
+
+	var r confirmSubscriptionResponse
+	if err := xml.Unmarshal(body, &r); err != nil {
+		return nil, ErrConfirmSubscription
 	}
+	return r.result(), nil
+}
 
-	return string(body), nil
+// ConfirmSubscriptionAuthenticated confirms the subscription with the SNS
+// ConfirmSubscription action and AuthenticateOnUnsubscribe, so that the
+// subscription cannot be removed by an unauthenticated request. It returns the
+// parsed result.
+func (c *Client) ConfirmSubscriptionAuthenticated(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+	return c.ConfirmSubscriptionAuthenticatedContext(context.Background(), msg)
+}
+
+// ConfirmSubscriptionAuthenticatedContext is ConfirmSubscriptionAuthenticated
+// with a context that stops the request and its retries.
+func (c *Client) ConfirmSubscriptionAuthenticatedContext(ctx context.Context, msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+	if c.credentials == nil {
+		return nil, ErrMissingCredentials
+	}
+	region, err := regionFromARN(msg.TopicArn)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []ManagerOption{WithManagerHTTPClient(c.httpClient), WithManagerRetryPolicy(c.retry)}
+	if c.apiEndpoint != "" {
+		opts = append(opts, WithEndpoint(c.apiEndpoint))
+	}
+	m := NewManager(region, *c.credentials, opts...)
+	return m.ConfirmSubscription(ctx, msg.TopicArn, msg.Token, true)
 }
 
 func (c *Client) ValidateCertURL(certURL string) error {
@@ -57,6 +154,11 @@ func (c *Client) ValidateCertURL(certURL string) error {
 }
 
 func (c *Client) CheckSignature(ms MessageSignature) error {
+	return c.CheckSignatureContext(context.Background(), ms)
+}
+
+// CheckSignatureContext is CheckSignature with a context for the cert fetch.
+func (c *Client) CheckSignatureContext(ctx context.Context, ms MessageSignature) error {
 	if ms.SignatureVersion != signatureVersion {
 		return ErrInvalidSignatureVersion
 	}
@@ -66,30 +168,89 @@ func (c *Client) CheckSignature(ms MessageSignature) error {
 		return err
 	}
 
-	res, err := http.Get(ms.SigningCertURL)
+	cert, err := c.signingCert(ctx, ms.SigningCertURL)
 	if err != nil {
 		return err
 	}
+
+	start := time.Now()
+	err = cert.CheckSignature(signatureAlgorithm, ms.Signed, signature)
+	c.metrics.ObserveSignatureVerification(time.Since(start), err)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// signingCert returns the cert at certURL, from the cache while it is valid.
+func (c *Client) signingCert(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	if cert, ok := c.certs.get(certURL, time.Now()); ok {
+		c.metrics.ObserveCertCache(true)
+		return cert, nil
+	}
+	c.metrics.ObserveCertCache(false)
+
+	var cert *x509.Certificate
+	start := time.Now()
+	err := trace(ctx, c.tracer, StageFetchCert, SpanInfo{}, func(ctx context.Context) (err error) {
+		cert, err = c.fetchCert(ctx, certURL)
+		return err
+	})
+	c.metrics.ObserveCertFetch(time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+	c.certs.put(certURL, cert)
+	return cert, nil
+}
+
+func (c *Client) fetchCert(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	res, err := c.retry.do(ctx, OpFetchCert, c.httpClient, getRequest(certURL))
+	if err != nil {
+		return nil, err
+	}
 	defer res.Body.Close()
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	p, _ := pem.Decode(body)
 	if p == nil {
-		return ErrInvalidCertBody
+		return nil, ErrInvalidCertBody
 	}
 
-	cert, err := x509.ParseCertificate(p.Bytes)
-	if err != nil {
-		return err
-	}
+	return x509.ParseCertificate(p.Bytes)
+}
 
-	if err := cert.CheckSignature(signatureAlgorithm, ms.Signed, signature); err != nil {
-		return ErrInvalidSignature
+type certCache struct {
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+func (cc *certCache) get(certURL string, now time.Time) (*x509.Certificate, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cert, ok := cc.certs[certURL]
+	if !ok {
+		return nil, false
 	}
+	if now.After(cert.NotAfter) {
+		delete(cc.certs, certURL)
+		return nil, false
+	}
+	return cert, true
+}
 
-	return nil
+func (cc *certCache) put(certURL string, cert *x509.Certificate) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if len(cc.certs) >= maxCachedCerts {
+		cc.certs = map[string]*x509.Certificate{}
+	}
+	cc.certs[certURL] = cert
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -12,23 +13,63 @@ func TestConfirmSubscription(t *testing.T) {
 	tests := map[string]struct {
 		msg     SubscriptionConfirmation
 		handler func(w http.ResponseWriter, r *http.Request)
-		want    string
+		want    *ConfirmSubscriptionResult
 		err     error
 	}{
 		"success": {
 			msg: SubscriptionConfirmation{},
 			handler: func(w http.ResponseWriter, r *http.Request) {
-				fmt.Fprintf(w, "ConfirmSubscription")
+				fmt.Fprintf(w, `<ConfirmSubscriptionResponse xmlns="http://sns.amazonaws.com/doc/2010-03-31/">
+  <ConfirmSubscriptionResult>
+    <SubscriptionArn>arn:aws:sns:us-west-2:123456789012:MyTopic:2bcfbf39-05c3-41de-beaa-fcfcc21c8f55</SubscriptionArn>
+  </ConfirmSubscriptionResult>
+  <ResponseMetadata>
+    <RequestId>075ecce8-8dac-11e1-bf80-f781d96e9307</RequestId>
+  </ResponseMetadata>
+</ConfirmSubscriptionResponse>`)
+			},
+			want: &ConfirmSubscriptionResult{
+				SubscriptionArn: "arn:aws:sns:us-west-2:123456789012:MyTopic:2bcfbf39-05c3-41de-beaa-fcfcc21c8f55",
+				RequestId:       "075ecce8-8dac-11e1-bf80-f781d96e9307",
 			},
-			want: "ConfirmSubscription",
-			err:  nil,
+			err: nil,
 		},
 		"Not_Found": {
 			msg: SubscriptionConfirmation{},
 			handler: func(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "404 not found", http.StatusNotFound)
 			},
-			want: "",
+			want: nil,
+			err:  ErrConfirmSubscription,
+		},
+		"ErrorResponse": {
+			msg: SubscriptionConfirmation{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprintf(w, `<ErrorResponse xmlns="http://sns.amazonaws.com/doc/2010-03-31/">
+  <Error>
+    <Type>Sender</Type>
+    <Code>AuthorizationError</Code>
+    <Message>Invalid token</Message>
+  </Error>
+  <RequestId>9a2c3f4e-1b2d-5e6f-8a9b-0c1d2e3f4a5b</RequestId>
+</ErrorResponse>`)
+			},
+			want: nil,
+			err: &APIError{
+				StatusCode: http.StatusForbidden,
+				Type:       "Sender",
+				Code:       "AuthorizationError",
+				Message:    "Invalid token",
+				RequestId:  "9a2c3f4e-1b2d-5e6f-8a9b-0c1d2e3f4a5b",
+			},
+		},
+		"invalid response": {
+			msg: SubscriptionConfirmation{},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "ConfirmSubscription")
+			},
+			want: nil,
 			err:  ErrConfirmSubscription,
 		},
 	}
@@ -44,11 +85,11 @@ func TestConfirmSubscription(t *testing.T) {
 			tt.msg.SubscribeURL = srv.URL
 			c := NewClient()
 			got, err := c.ConfirmSubscription(tt.msg)
-			if err != tt.err {
+			if !reflect.DeepEqual(err, tt.err) {
 				t.Errorf("err = %v, want %v", err, tt.err)
 			}
-			if got != tt.want {
-				t.Errorf("NewMessageType() = %v, want %v", got, tt.want)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConfirmSubscription() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -333,3 +374,98 @@ func TestCheckSignature(t *testing.T) {
 		})
 	}
 }
+
+func TestConfirmSubscriptionAuthenticated(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		msg     SubscriptionConfirmation
+		opts    []ClientOption
+		status  int
+		handler func(w http.ResponseWriter, r *http.Request)
+		want    *ConfirmSubscriptionResult
+		wantErr bool
+	}{
+		"success": {
+			msg: SubscriptionConfirmation{
+				TopicArn: "arn:aws:sns:us-west-2:123456789012:MyTopic",
+				Token:    "Ethevee8dae4mie3",
+			},
+			opts: []ClientOption{WithCredentials(Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"})},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Error(err)
+				}
+				if r.PostForm.Get("Action") != "ConfirmSubscription" ||
+					r.PostForm.Get("Token") != "Ethevee8dae4mie3" ||
+					r.PostForm.Get("TopicArn") != "arn:aws:sns:us-west-2:123456789012:MyTopic" ||
+					r.PostForm.Get("AuthenticateOnUnsubscribe") != "true" {
+					t.Errorf("params = %v", r.PostForm)
+				}
+				if !strings.Contains(r.Header.Get("Authorization"), "/us-west-2/sns/aws4_request") {
+					t.Errorf("Authorization = %v", r.Header.Get("Authorization"))
+				}
+				fmt.Fprint(w, `<ConfirmSubscriptionResponse xmlns="https://sns.amazonaws.com/doc/2010-03-31/">
+  <ConfirmSubscriptionResult>
+    <SubscriptionArn>arn:aws:sns:us-west-2:123456789012:MyTopic:80289ba6-0fd4-4079-afb4-ce8c8260f0ca</SubscriptionArn>
+  </ConfirmSubscriptionResult>
+  <ResponseMetadata>
+    <RequestId>7a50221f-3774-11df-a9b7-05d48da6f042</RequestId>
+  </ResponseMetadata>
+</ConfirmSubscriptionResponse>`)
+			},
+			want: &ConfirmSubscriptionResult{
+				SubscriptionArn: "arn:aws:sns:us-west-2:123456789012:MyTopic:80289ba6-0fd4-4079-afb4-ce8c8260f0ca",
+				RequestId:       "7a50221f-3774-11df-a9b7-05d48da6f042",
+			},
+		},
+		"missing credentials": {
+			msg: SubscriptionConfirmation{
+				TopicArn: "arn:aws:sns:us-west-2:123456789012:MyTopic",
+				Token:    "Ethevee8dae4mie3",
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			wantErr: true,
+		},
+		"invalid topic arn": {
+			msg: SubscriptionConfirmation{
+				TopicArn: "MyTopic",
+				Token:    "Ethevee8dae4mie3",
+			},
+			opts:    []ClientOption{WithCredentials(Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"})},
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			wantErr: true,
+		},
+		"error response": {
+			msg: SubscriptionConfirmation{
+				TopicArn: "arn:aws:sns:us-west-2:123456789012:MyTopic",
+				Token:    "Ethevee8dae4mie3",
+			},
+			opts: []ClientOption{WithCredentials(Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"})},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>InvalidParameter</Code><Message>Invalid token</Message></Error><RequestId>1</RequestId></ErrorResponse>`)
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(http.HandlerFunc(tt.handler))
+			defer srv.Close()
+
+			c := NewClient(append(tt.opts, WithAPIEndpoint(srv.URL))...)
+			got, err := c.ConfirmSubscriptionAuthenticated(tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConfirmSubscriptionAuthenticated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+// Package snschi adapts sns.Middleware to chi and other http.Handler based routers.
+package snschi
+
+import (
+	"net/http"
+
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+)
+
+// Subscribe returns middleware for chi.Router.Use that verifies SNS messages
+// for snsTopicARN. The verified Notification is stored in the request context.
+func Subscribe(m *sns.Middleware, snsTopicARN string) func(http.Handler) http.Handler {
+	return m.Handler(snsTopicARN)
+}
+
+func GetNotification(r *http.Request) (sns.Notification, error) {
+	return sns.GetNotification(r)
+}
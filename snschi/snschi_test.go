@@ -0,0 +1,32 @@
+package snschi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/snstest"
+)
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+
+	snstest.RunSubscribeTests(t, func(t *testing.T, m *sns.Middleware, topicARN string, r *http.Request) *http.Response {
+		router := chi.NewRouter()
+		router.Use(Subscribe(m, topicARN))
+		router.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			msg, err := GetNotification(r)
+			if err != nil {
+				t.Errorf("err should be nil, but got %q", err)
+			}
+			fmt.Fprint(w, msg.Message)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Result()
+	})
+}
@@ -0,0 +1,37 @@
+// Package snsecho adapts sns.Middleware to echo.
+package snsecho
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/adapter"
+)
+
+const ContextKeyNotification = adapter.ContextKeyNotification
+
+// Subscribe returns echo middleware that verifies SNS messages for snsTopicARN.
+// The verified Notification is stored in the echo.Context.
+func Subscribe(m *sns.Middleware, snsTopicARN string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var err error
+			m.Subscribe(snsTopicARN)(func(w http.ResponseWriter, r *http.Request) {
+				c.SetRequest(r)
+				if msg, ok := sns.FromContext(r.Context()); ok {
+					c.Set(ContextKeyNotification, msg)
+				}
+				err = next(c)
+			})(c.Response(), c.Request())
+			return err
+		}
+	}
+}
+
+func GetNotification(c echo.Context) (sns.Notification, error) {
+	if msg, ok := c.Get(ContextKeyNotification).(sns.Notification); ok {
+		return msg, nil
+	}
+	return sns.Notification{}, sns.ErrNotFoundNotification
+}
@@ -0,0 +1,30 @@
+package snsecho
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/snstest"
+)
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+
+	snstest.RunSubscribeTests(t, func(t *testing.T, m *sns.Middleware, topicARN string, r *http.Request) *http.Response {
+		e := echo.New()
+		e.POST("/", func(c echo.Context) error {
+			msg, err := GetNotification(c)
+			if err != nil {
+				t.Errorf("err should be nil, but got %q", err)
+			}
+			return c.String(http.StatusOK, msg.Message)
+		}, Subscribe(m, topicARN))
+
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, r)
+		return w.Result()
+	})
+}
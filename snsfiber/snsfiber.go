@@ -0,0 +1,74 @@
+// Package snsfiber adapts sns.Middleware to fiber.
+package snsfiber
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/adapter"
+)
+
+const ContextKeyNotification = adapter.ContextKeyNotification
+
+// Subscribe returns fiber middleware that verifies SNS messages for snsTopicARN.
+// The verified Notification is stored in the fiber.Ctx locals, and the context
+// of the verified request, which carries the raw envelope, VerificationInfo and
+// trace context, is set as the user context.
+func Subscribe(m *sns.Middleware, snsTopicARN string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var r http.Request
+		if err := fasthttpadaptor.ConvertRequest(c.Context(), &r, true); err != nil {
+			return err
+		}
+
+		w := &responseWriter{header: http.Header{}, status: http.StatusOK}
+		next := false
+		m.Subscribe(snsTopicARN)(func(_ http.ResponseWriter, r *http.Request) {
+			next = true
+			c.SetUserContext(r.Context())
+			if msg, ok := sns.FromContext(r.Context()); ok {
+				c.Locals(ContextKeyNotification, msg)
+			}
+		})(w, &r)
+		if next {
+			return c.Next()
+		}
+
+		for k, vs := range w.header {
+			for _, v := range vs {
+				c.Response().Header.Add(k, v)
+			}
+		}
+		return c.Status(w.status).Send(w.body.Bytes())
+	}
+}
+
+func GetNotification(c *fiber.Ctx) (sns.Notification, error) {
+	if msg, ok := c.Locals(ContextKeyNotification).(sns.Notification); ok {
+		return msg, nil
+	}
+	return sns.Notification{}, sns.ErrNotFoundNotification
+}
+
+// responseWriter records the response written by the middleware when it
+// rejects a request.
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.status = status
+}
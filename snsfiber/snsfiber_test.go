@@ -0,0 +1,57 @@
+package snsfiber
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/snstest"
+)
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+
+	snstest.RunSubscribeTests(t, func(t *testing.T, m *sns.Middleware, topicARN string, r *http.Request) *http.Response {
+		app := fiber.New()
+		app.Post("/", Subscribe(m, topicARN), func(c *fiber.Ctx) error {
+			msg, err := GetNotification(c)
+			if err != nil {
+				t.Errorf("err should be nil, but got %q", err)
+			}
+			return c.SendString(msg.Message)
+		})
+
+		resp, err := app.Test(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	})
+}
+
+func TestSubscribe_UserContext(t *testing.T) {
+	t.Parallel()
+
+	m := sns.NewMiddleware(sns.WithClient(snstest.NewClient(t)))
+	app := fiber.New()
+	app.Post("/", Subscribe(m, snstest.TopicARN), func(c *fiber.Ctx) error {
+		raw, ok := sns.RawEnvelopeFromContext(c.UserContext())
+		if !ok || string(raw) != snstest.NotificationBody {
+			t.Errorf("RawEnvelopeFromContext() = %q, %v, want %q", raw, ok, snstest.NotificationBody)
+		}
+		info, ok := sns.VerificationInfoFromContext(c.UserContext())
+		if !ok || info.TopicArn != snstest.TopicARN {
+			t.Errorf("VerificationInfoFromContext() = %+v, %v", info, ok)
+		}
+		return c.SendStatus(http.StatusOK)
+	})
+
+	resp, err := app.Test(snstest.NewRequest(snstest.NotificationBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+}
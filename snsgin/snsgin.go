@@ -0,0 +1,39 @@
+// Package snsgin adapts sns.Middleware to gin.
+package snsgin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/adapter"
+)
+
+const ContextKeyNotification = adapter.ContextKeyNotification
+
+// Subscribe returns gin middleware that verifies SNS messages for snsTopicARN.
+// The verified Notification is stored in the gin.Context.
+func Subscribe(m *sns.Middleware, snsTopicARN string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		next := false
+		m.Subscribe(snsTopicARN)(func(w http.ResponseWriter, r *http.Request) {
+			next = true
+			c.Request = r
+			if msg, ok := sns.FromContext(r.Context()); ok {
+				c.Set(ContextKeyNotification, msg)
+			}
+			c.Next()
+		})(c.Writer, c.Request)
+		if !next {
+			c.Abort()
+		}
+	}
+}
+
+func GetNotification(c *gin.Context) (sns.Notification, error) {
+	v, _ := c.Get(ContextKeyNotification)
+	if msg, ok := v.(sns.Notification); ok {
+		return msg, nil
+	}
+	return sns.Notification{}, sns.ErrNotFoundNotification
+}
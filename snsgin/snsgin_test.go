@@ -0,0 +1,31 @@
+package snsgin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/snstest"
+)
+
+func TestSubscribe(t *testing.T) {
+	t.Parallel()
+	gin.SetMode(gin.TestMode)
+
+	snstest.RunSubscribeTests(t, func(t *testing.T, m *sns.Middleware, topicARN string, r *http.Request) *http.Response {
+		router := gin.New()
+		router.POST("/", Subscribe(m, topicARN), func(c *gin.Context) {
+			msg, err := GetNotification(c)
+			if err != nil {
+				t.Errorf("err should be nil, but got %q", err)
+			}
+			c.String(http.StatusOK, msg.Message)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Result()
+	})
+}
@@ -0,0 +1,245 @@
+// Package snslambda verifies SNS messages delivered to AWS Lambda, either through
+// API Gateway and Lambda function URLs or by a direct SNS subscription.
+package snslambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+)
+
+type Handler struct {
+	client      *sns.Client
+	opts        []sns.Option
+	snsTopicARN string
+	handler     sns.NotificationHandler
+	dispatcher  http.Handler
+}
+
+type Option func(*Handler)
+
+// WithClient sets the Client used to verify messages and confirm subscriptions.
+func WithClient(c *sns.Client) Option {
+	return func(h *Handler) {
+		h.client = c
+	}
+}
+
+// WithMiddlewareOptions configures the Middleware that handles API Gateway and
+// Lambda function URL events, for example with sns.WithMetrics or
+// sns.WithAuditSink. The Client is set with WithClient.
+func WithMiddlewareOptions(opts ...sns.Option) Option {
+	return func(h *Handler) {
+		h.opts = append(h.opts, opts...)
+	}
+}
+
+// NewHandler returns a Handler that verifies messages for snsTopicARN and passes
+// each Notification to h, the same NotificationHandler used with Middleware.Dispatch.
+func NewHandler(snsTopicARN string, h sns.NotificationHandler, opts ...Option) *Handler {
+	hd := &Handler{
+		client:      sns.NewClient(),
+		snsTopicARN: snsTopicARN,
+		handler:     h,
+	}
+	for _, opt := range opts {
+		opt(hd)
+	}
+	m := sns.NewMiddleware(append([]sns.Option{sns.WithClient(hd.client)}, hd.opts...)...)
+	hd.dispatcher = m.Dispatch(snsTopicARN, h)
+	return hd
+}
+
+// SNSEvent is an events.SNSEvent that also keeps the Timestamp of each record
+// as it was received. events.SNSEntity parses it to a time.Time, and the
+// signature has to be checked against the original string.
+type SNSEvent struct {
+	events.SNSEvent
+	timestamps []string
+}
+
+func (e *SNSEvent) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &e.SNSEvent); err != nil {
+		return err
+	}
+	var raw struct {
+		Records []struct {
+			SNS struct {
+				Timestamp string
+			} `json:"Sns"`
+		}
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	e.timestamps = make([]string, len(raw.Records))
+	for i, r := range raw.Records {
+		e.timestamps[i] = r.SNS.Timestamp
+	}
+	return nil
+}
+
+// timestamp returns the Timestamp of the i-th record. Records that were not
+// unmarshaled from JSON fall back to the format SNS uses.
+func (e *SNSEvent) timestamp(i int) (sns.Timestamp, error) {
+	if i < len(e.timestamps) && e.timestamps[i] != "" {
+		return sns.ParseTimestamp(e.timestamps[i])
+	}
+	return sns.NewTimestamp(e.Records[i].SNS.Timestamp), nil
+}
+
+// HandleSNSEvent handles records delivered by a direct SNS to Lambda subscription.
+// It stops at the first record that fails so that Lambda retries the event.
+func (h *Handler) HandleSNSEvent(ctx context.Context, event SNSEvent) error {
+	for i, record := range event.Records {
+		msg, err := notification(record.SNS)
+		if err != nil {
+			return err
+		}
+		if msg.Timestamp, err = event.timestamp(i); err != nil {
+			return err
+		}
+		if msg.TopicArn != h.snsTopicARN {
+			return sns.ErrInvalidTopicArn
+		}
+		if err := h.client.ValidateCertURL(msg.SigningCertURL); err != nil {
+			return err
+		}
+		if err := h.client.CheckSignature(msg.MessageSignature()); err != nil {
+			return err
+		}
+		if err := h.handler.HandleNotification(sns.NewContext(ctx, msg), msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleAPIGatewayProxy handles an API Gateway REST API (v1) proxy event.
+func (h *Handler) HandleAPIGatewayProxy(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	header := http.Header{}
+	for k, v := range req.Headers {
+		header.Set(k, v)
+	}
+	for k, vs := range req.MultiValueHeaders {
+		header.Del(k)
+		for _, v := range vs {
+			header.Add(k, v)
+		}
+	}
+
+	w, err := h.serve(ctx, req.HTTPMethod, req.Path, header, req.Body, req.IsBase64Encoded)
+	if err != nil {
+		return events.APIGatewayProxyResponse{}, err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode:        w.code,
+		MultiValueHeaders: w.header,
+		Body:              w.body.String(),
+	}, nil
+}
+
+// HandleAPIGatewayV2HTTP handles an API Gateway HTTP API (v2) or Lambda function URL event.
+func (h *Handler) HandleAPIGatewayV2HTTP(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	header := http.Header{}
+	for k, v := range req.Headers {
+		header.Set(k, v)
+	}
+
+	w, err := h.serve(ctx, req.RequestContext.HTTP.Method, req.RawPath, header, req.Body, req.IsBase64Encoded)
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{}, err
+	}
+	headers := map[string]string{}
+	for k := range w.header {
+		headers[k] = strings.Join(w.header.Values(k), ",")
+	}
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode: w.code,
+		Headers:    headers,
+		Body:       w.body.String(),
+	}, nil
+}
+
+func (h *Handler) serve(ctx context.Context, method, path string, header http.Header, body string, isBase64Encoded bool) (*responseRecorder, error) {
+	if isBase64Encoded {
+		b, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(b)
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, path, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header = header
+
+	w := newResponseRecorder()
+	h.dispatcher.ServeHTTP(w, r)
+	return w, nil
+}
+
+func notification(e events.SNSEntity) (sns.Notification, error) {
+	msg := sns.Notification{
+		Type:             e.Type,
+		MessageId:        e.MessageID,
+		TopicArn:         e.TopicArn,
+		Subject:          e.Subject,
+		Message:          e.Message,
+		SignatureVersion: e.SignatureVersion,
+		Signature:        e.Signature,
+		SigningCertURL:   e.SigningCertURL,
+		UnsubscribeURL:   e.UnsubscribeURL,
+	}
+	if len(e.MessageAttributes) > 0 {
+		b, err := json.Marshal(e.MessageAttributes)
+		if err != nil {
+			return sns.Notification{}, err
+		}
+		if err := json.Unmarshal(b, &msg.MessageAttributes); err != nil {
+			return sns.Notification{}, err
+		}
+	}
+	return msg, nil
+}
+
+// responseRecorder keeps the response written by the middleware so that it can
+// be returned as an API Gateway response.
+type responseRecorder struct {
+	header      http.Header
+	code        int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: http.Header{}, code: http.StatusOK}
+}
+
+func (w *responseRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+func (w *responseRecorder) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.code = code
+}
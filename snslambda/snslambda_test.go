@@ -0,0 +1,294 @@
+package snslambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/snstest"
+)
+
+func loadEvent(t *testing.T, name string, v interface{}) {
+	t.Helper()
+
+	b, err := os.ReadFile(snstest.Testdata("lambda/" + name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandler_HandleSNSEvent(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		prepare    func(e *events.SNSEvent)
+		topicARN   string
+		wantCalled bool
+		want       error
+	}{
+		"success": {
+			prepare:    func(e *events.SNSEvent) {},
+			topicARN:   snstest.TopicARN,
+			wantCalled: true,
+			want:       nil,
+		},
+		"invalid signature": {
+			prepare: func(e *events.SNSEvent) {
+				e.Records[0].SNS.Message = "Invalid message"
+			},
+			topicARN:   snstest.TopicARN,
+			wantCalled: false,
+			want:       sns.ErrInvalidSignature,
+		},
+		"invalid cert url": {
+			prepare: func(e *events.SNSEvent) {
+				e.Records[0].SNS.SigningCertURL = "https://example.com/cert.pem"
+			},
+			topicARN:   snstest.TopicARN,
+			wantCalled: false,
+			want:       sns.ErrInvalidCertURLHost,
+		},
+		"invalid topic arn": {
+			prepare:    func(e *events.SNSEvent) {},
+			topicARN:   "arn:aws:sns:us-west-2:123456789012:OtherTopic",
+			wantCalled: false,
+			want:       sns.ErrInvalidTopicArn,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var event SNSEvent
+			loadEvent(t, "sns_event.json", &event)
+			tt.prepare(&event.SNSEvent)
+
+			called := false
+			h := NewHandler(tt.topicARN, sns.NotificationHandlerFunc(func(ctx context.Context, msg sns.Notification) error {
+				called = true
+				if msg.Message != "Hello world!" {
+					t.Errorf("Message = %v, want %v", msg.Message, "Hello world!")
+				}
+				if _, ok := sns.FromContext(ctx); !ok {
+					t.Error("Notification should be stored in ctx")
+				}
+				return nil
+			}), WithClient(snstest.NewClient(t)))
+
+			if err := h.HandleSNSEvent(context.Background(), event); !errors.Is(err, tt.want) {
+				t.Errorf("HandleSNSEvent() = %v, want %v", err, tt.want)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("called = %v, want %v", called, tt.wantCalled)
+			}
+		})
+	}
+}
+
+func TestHandler_HandleSNSEvent_Timestamp(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		timestamp string
+	}{
+		{
+			name:      "milliseconds",
+			timestamp: "2012-05-02T00:54:06.655Z",
+		},
+		{
+			name:      "seconds",
+			timestamp: "2012-05-02T00:54:06Z",
+		},
+		{
+			name:      "microseconds",
+			timestamp: "2012-05-02T00:54:06.655001Z",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts, err := sns.ParseTimestamp(tt.timestamp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var event SNSEvent
+			loadEvent(t, "sns_event.json", &event)
+			msg, err := notification(event.Records[0].SNS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg.Timestamp = ts
+
+			b, err := os.ReadFile(snstest.Testdata("lambda/sns_event.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b = bytes.Replace(b, []byte(`"2012-05-02T00:54:06.655Z"`), []byte(strconv.Quote(tt.timestamp)), 1)
+			b = bytes.Replace(b, []byte(strconv.Quote(msg.Signature)), []byte(strconv.Quote(snstest.Sign(t, msg.MessageSignature().Signed))), 1)
+			event = SNSEvent{}
+			if err := json.Unmarshal(b, &event); err != nil {
+				t.Fatal(err)
+			}
+
+			h := NewHandler(snstest.TopicARN, sns.NotificationHandlerFunc(func(ctx context.Context, msg sns.Notification) error {
+				if msg.Timestamp.String() != tt.timestamp {
+					t.Errorf("Timestamp = %v, want %v", msg.Timestamp, tt.timestamp)
+				}
+				return nil
+			}), WithClient(snstest.NewClient(t)))
+			if err := h.HandleSNSEvent(context.Background(), event); err != nil {
+				t.Errorf("HandleSNSEvent() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestHandler_HandleAPIGatewayProxy(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		prepare        func(r *events.APIGatewayProxyRequest)
+		wantCalled     bool
+		wantStatusCode int
+	}{
+		"success": {
+			prepare:        func(r *events.APIGatewayProxyRequest) {},
+			wantCalled:     true,
+			wantStatusCode: http.StatusOK,
+		},
+		"invalid signature": {
+			prepare: func(r *events.APIGatewayProxyRequest) {
+				r.Body = snstest.TamperedNotificationBody
+			},
+			wantCalled:     false,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var req events.APIGatewayProxyRequest
+			loadEvent(t, "apigw_v1.json", &req)
+			tt.prepare(&req)
+
+			called := false
+			h := NewHandler(snstest.TopicARN, sns.NotificationHandlerFunc(func(ctx context.Context, msg sns.Notification) error {
+				called = true
+				return nil
+			}), WithClient(snstest.NewClient(t)))
+
+			resp, err := h.HandleAPIGatewayProxy(context.Background(), req)
+			if err != nil {
+				t.Errorf("err should be nil, but got %q", err)
+			}
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("HandleAPIGatewayProxy() = %v, want %v", resp.StatusCode, tt.wantStatusCode)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("called = %v, want %v", called, tt.wantCalled)
+			}
+		})
+	}
+}
+
+func TestHandler_WithMiddlewareOptions(t *testing.T) {
+	t.Parallel()
+
+	var req events.APIGatewayProxyRequest
+	loadEvent(t, "apigw_v1.json", &req)
+
+	var logged []sns.Outcome
+	h := NewHandler(snstest.TopicARN, sns.NotificationHandlerFunc(func(ctx context.Context, msg sns.Notification) error {
+		return nil
+	}),
+		WithClient(snstest.NewClient(t)),
+		WithMiddlewareOptions(sns.WithLogger(sns.LoggerFunc(func(ctx context.Context, rec sns.LogRecord) {
+			logged = append(logged, rec.Outcome)
+		}))),
+	)
+
+	resp, err := h.HandleAPIGatewayProxy(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("HandleAPIGatewayProxy() = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+	if len(logged) != 1 || logged[0] != sns.OutcomeOK {
+		t.Errorf("logged = %v, want [%v]", logged, sns.OutcomeOK)
+	}
+}
+
+func TestHandler_HandleAPIGatewayV2HTTP(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		prepare        func(r *events.APIGatewayV2HTTPRequest)
+		wantCalled     bool
+		wantStatusCode int
+	}{
+		"success": {
+			prepare:        func(r *events.APIGatewayV2HTTPRequest) {},
+			wantCalled:     true,
+			wantStatusCode: http.StatusOK,
+		},
+		"invalid signature": {
+			prepare: func(r *events.APIGatewayV2HTTPRequest) {
+				r.Body = snstest.TamperedNotificationBody
+				r.IsBase64Encoded = false
+			},
+			wantCalled:     false,
+			wantStatusCode: http.StatusForbidden,
+		},
+		"invalid topic arn": {
+			prepare: func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers["x-amz-sns-topic-arn"] = "arn:aws:sns:us-west-2:123456789012:OtherTopic"
+			},
+			wantCalled:     false,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var req events.APIGatewayV2HTTPRequest
+			loadEvent(t, "apigw_v2.json", &req)
+			tt.prepare(&req)
+
+			called := false
+			h := NewHandler(snstest.TopicARN, sns.NotificationHandlerFunc(func(ctx context.Context, msg sns.Notification) error {
+				called = true
+				return nil
+			}), WithClient(snstest.NewClient(t)))
+
+			resp, err := h.HandleAPIGatewayV2HTTP(context.Background(), req)
+			if err != nil {
+				t.Errorf("err should be nil, but got %q", err)
+			}
+			if resp.StatusCode != tt.wantStatusCode {
+				t.Errorf("HandleAPIGatewayV2HTTP() = %v, want %v", resp.StatusCode, tt.wantStatusCode)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("called = %v, want %v", called, tt.wantCalled)
+			}
+		})
+	}
+}
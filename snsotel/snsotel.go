@@ -0,0 +1,180 @@
+// Package snsotel traces the sns middleware pipeline with OpenTelemetry.
+package snsotel
+
+import (
+	"context"
+	"strings"
+
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentationName = "github.com/yasszu/aws-sns-subscrube-https-go/snsotel"
+
+	// AWSTraceHeader is the message attribute carrying an X-Ray trace header.
+	AWSTraceHeader = "AWSTraceHeader"
+)
+
+var _ sns.Tracer = (*Tracer)(nil)
+
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+type Option func(*config)
+
+type config struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// WithTracerProvider sets the provider of the tracer. The global provider is
+// used unless set.
+func WithTracerProvider(p trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = p
+	}
+}
+
+// WithPropagator sets the propagator used to extract trace context from
+// MessageAttributes. W3C Trace Context is used unless set.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = p
+	}
+}
+
+func NewTracer(opts ...Option) *Tracer {
+	c := &config{
+		provider:   otel.GetTracerProvider(),
+		propagator: propagation.TraceContext{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return &Tracer{
+		tracer:     c.provider.Tracer(instrumentationName),
+		propagator: c.propagator,
+	}
+}
+
+func (t *Tracer) Start(ctx context.Context, stage sns.Stage, info sns.SpanInfo) (context.Context, sns.Span) {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKey.String("aws_sns"),
+		semconv.MessagingDestinationKindTopic,
+	}
+	if info.TopicArn != "" {
+		attrs = append(attrs, semconv.MessagingDestinationKey.String(info.TopicArn))
+	}
+	if info.MessageId != "" {
+		attrs = append(attrs, semconv.MessagingMessageIDKey.String(info.MessageId))
+	}
+	if info.MessageType != 0 {
+		attrs = append(attrs, attribute.String("aws.sns.message_type", info.MessageType.String()))
+	}
+
+	opts := []trace.SpanStartOption{trace.WithAttributes(attrs...)}
+	if stage == sns.StageHandler {
+		opts = append(opts,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(semconv.MessagingOperationProcess),
+		)
+		if sc, ok := ctx.Value(receiverKey{}).(trace.SpanContext); ok {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+		}
+	}
+
+	ctx, span := t.tracer.Start(ctx, "sns "+string(stage), opts...)
+	return ctx, otelSpan{span}
+}
+
+// receiverKey keeps the span that received the message when Extract replaces
+// the parent, so that the handler span links to it.
+type receiverKey struct{}
+
+// Extract returns ctx with the trace context found in the MessageAttributes
+// of msg, read with the propagator or from the AWSTraceHeader attribute.
+func (t *Tracer) Extract(ctx context.Context, msg sns.Notification) context.Context {
+	carrier := propagation.MapCarrier{}
+	for k, v := range msg.MessageAttributes {
+		carrier[strings.ToLower(k)] = v.Value
+	}
+
+	remote := trace.SpanContextFromContext(t.propagator.Extract(context.Background(), carrier))
+	if !remote.IsValid() {
+		if v, ok := msg.MessageAttributes[AWSTraceHeader]; ok {
+			remote, _ = parseAWSTraceHeader(v.Value)
+		}
+	}
+	if !remote.IsValid() {
+		return ctx
+	}
+
+	if local := trace.SpanContextFromContext(ctx); local.IsValid() {
+		ctx = context.WithValue(ctx, receiverKey{}, local)
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, remote)
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+// parseAWSTraceHeader parses an X-Ray trace header such as
+// "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1".
+func parseAWSTraceHeader(header string) (trace.SpanContext, bool) {
+	var (
+		cfg   trace.SpanContextConfig
+		valid = 0
+	)
+	for _, part := range strings.Split(header, ";") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "Root":
+			fields := strings.Split(kv[1], "-")
+			if len(fields) != 3 || fields[0] != "1" {
+				return trace.SpanContext{}, false
+			}
+			id, err := trace.TraceIDFromHex(fields[1] + fields[2])
+			if err != nil {
+				return trace.SpanContext{}, false
+			}
+			cfg.TraceID = id
+			valid++
+		case "Parent":
+			id, err := trace.SpanIDFromHex(kv[1])
+			if err != nil {
+				return trace.SpanContext{}, false
+			}
+			cfg.SpanID = id
+			valid++
+		case "Sampled":
+			if kv[1] == "1" {
+				cfg.TraceFlags = trace.FlagsSampled
+			}
+		}
+	}
+	if valid != 2 {
+		return trace.SpanContext{}, false
+	}
+	cfg.Remote = true
+	return trace.NewSpanContext(cfg), true
+}
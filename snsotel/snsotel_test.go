@@ -0,0 +1,174 @@
+package snsotel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/snstest"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		attributes    string
+		wantTraceID   string
+		wantLinkCount int
+	}{
+		{
+			name:          "it starts the handler span from traceparent",
+			attributes:    `{"traceparent":{"Type":"String","Value":"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}`,
+			wantTraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantLinkCount: 1,
+		},
+		{
+			name:          "it starts the handler span from AWSTraceHeader",
+			attributes:    `{"AWSTraceHeader":{"Type":"String","Value":"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"}}`,
+			wantTraceID:   "5759e988bd862e3fe1be46a994272793",
+			wantLinkCount: 1,
+		},
+		{
+			name:          "it keeps the request trace without trace context",
+			wantLinkCount: 0,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			tracer := NewTracer(WithTracerProvider(provider))
+
+			client := sns.NewClient(
+				sns.WithHTTPClient(snstest.NewHTTPClient(t)),
+				sns.WithClientTracer(tracer),
+			)
+			m := sns.NewMiddleware(sns.WithClient(client), sns.WithTracer(tracer))
+
+			var handlerSpan trace.SpanContext
+			h := m.Handler(snstest.TopicARN)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerSpan = trace.SpanContextFromContext(r.Context())
+			}))
+
+			body := snstest.NotificationBody
+			if tt.attributes != "" {
+				body = strings.TrimSuffix(body, "}") + `,"MessageAttributes":` + tt.attributes + `}`
+			}
+			req := snstest.NewRequest(body)
+			ctx, parent := provider.Tracer("test").Start(req.Context(), "request")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req.WithContext(ctx))
+			parent.End()
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %v, want %v", w.Code, http.StatusOK)
+			}
+
+			spans := map[string]sdktrace.ReadOnlySpan{}
+			for _, s := range recorder.Ended() {
+				spans[s.Name()] = s
+			}
+			for _, name := range []string{"sns decode", "sns validate-cert-url", "sns check-signature", "sns fetch-cert", "sns handler"} {
+				if _, ok := spans[name]; !ok {
+					t.Errorf("span %q not found", name)
+				}
+			}
+
+			handler := spans["sns handler"]
+			if handler == nil {
+				return
+			}
+			if !handler.SpanContext().Equal(handlerSpan) {
+				t.Errorf("handler context span = %v, want %v", handlerSpan, handler.SpanContext())
+			}
+			wantTraceID := tt.wantTraceID
+			if wantTraceID == "" {
+				wantTraceID = parent.SpanContext().TraceID().String()
+			}
+			if got := handler.SpanContext().TraceID().String(); got != wantTraceID {
+				t.Errorf("handler trace ID = %v, want %v", got, wantTraceID)
+			}
+			if got := len(handler.Links()); got != tt.wantLinkCount {
+				t.Errorf("handler links = %v, want %v", got, tt.wantLinkCount)
+			}
+			if handler.SpanKind() != trace.SpanKindConsumer {
+				t.Errorf("handler kind = %v, want %v", handler.SpanKind(), trace.SpanKindConsumer)
+			}
+
+			attrs := map[attribute.Key]string{}
+			for _, kv := range handler.Attributes() {
+				attrs[kv.Key] = kv.Value.Emit()
+			}
+			want := map[attribute.Key]string{
+				"messaging.system":           "aws_sns",
+				"messaging.destination":      snstest.TopicARN,
+				"messaging.destination_kind": "topic",
+				"messaging.message_id":       "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+				"messaging.operation":        "process",
+			}
+			for k, v := range want {
+				if attrs[k] != v {
+					t.Errorf("attribute %s = %q, want %q", k, attrs[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestTracer_CheckSignatureFailed(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := NewTracer(WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+	m := sns.NewMiddleware(sns.WithClient(snstest.NewClient(t)), sns.WithTracer(tracer))
+
+	w := httptest.NewRecorder()
+	m.Handler(snstest.TopicARN)(http.NotFoundHandler()).ServeHTTP(w, snstest.NewRequest(snstest.TamperedNotificationBody))
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %v, want %v", w.Code, http.StatusForbidden)
+	}
+	for _, s := range recorder.Ended() {
+		if s.Name() == "sns handler" {
+			t.Errorf("handler span should not be started")
+		}
+		if s.Name() == "sns check-signature" && s.Status().Description != sns.ErrInvalidSignature.Error() {
+			t.Errorf("check-signature status = %v, want %v", s.Status(), sns.ErrInvalidSignature)
+		}
+	}
+}
+
+func TestParseAWSTraceHeader(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		header    string
+		wantValid bool
+	}{
+		"valid":          {header: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1", wantValid: true},
+		"without parent": {header: "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1", wantValid: false},
+		"invalid root":   {header: "Root=2-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8", wantValid: false},
+		"empty":          {header: "", wantValid: false},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sc, ok := parseAWSTraceHeader(tt.header)
+			if ok != tt.wantValid || sc.IsValid() != tt.wantValid {
+				t.Errorf("parseAWSTraceHeader() = %v, %v, want valid %v", sc, ok, tt.wantValid)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Package snsprom exposes the sns.Metrics of the middleware and client as
+// Prometheus metrics.
+package snsprom
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+)
+
+const namespace = "sns"
+
+var (
+	_ sns.Metrics          = (*Collector)(nil)
+	_ prometheus.Collector = (*Collector)(nil)
+)
+
+// Collector is an sns.Metrics that is also a prometheus.Collector.
+type Collector struct {
+	messages          *prometheus.CounterVec
+	certFetch         *prometheus.HistogramVec
+	signatureVerify   *prometheus.HistogramVec
+	certCache         *prometheus.CounterVec
+	certCacheHitRatio prometheus.GaugeFunc
+
+	hits   uint64
+	misses uint64
+}
+
+func NewCollector() *Collector {
+	c := &Collector{
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_total",
+			Help:      "Number of SNS messages received, by topic ARN, message type and outcome.",
+		}, []string{"topic_arn", "type", "outcome"}),
+		certFetch: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "cert_fetch_duration_seconds",
+			Help:      "Time spent fetching signing certs.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+		signatureVerify: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "signature_verification_duration_seconds",
+			Help:      "Time spent verifying message signatures.",
+			Buckets:   []float64{.00005, .0001, .00025, .0005, .001, .0025, .005, .01},
+		}, []string{"result"}),
+		certCache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cert_cache_lookups_total",
+			Help:      "Number of signing cert cache lookups, by result.",
+		}, []string{"result"}),
+	}
+	c.certCacheHitRatio = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cert_cache_hit_ratio",
+		Help:      "Ratio of signing cert cache lookups that were hits.",
+	}, c.hitRatio)
+	return c
+}
+
+func (c *Collector) ObserveMessage(topicArn string, messageType sns.MessageType, outcome sns.Outcome) {
+	c.messages.WithLabelValues(topicArn, messageType.String(), string(outcome)).Inc()
+}
+
+func (c *Collector) ObserveCertFetch(d time.Duration, err error) {
+	c.certFetch.WithLabelValues(result(err)).Observe(d.Seconds())
+}
+
+func (c *Collector) ObserveSignatureVerification(d time.Duration, err error) {
+	c.signatureVerify.WithLabelValues(result(err)).Observe(d.Seconds())
+}
+
+func (c *Collector) ObserveCertCache(hit bool) {
+	if hit {
+		atomic.AddUint64(&c.hits, 1)
+		c.certCache.WithLabelValues("hit").Inc()
+		return
+	}
+	atomic.AddUint64(&c.misses, 1)
+	c.certCache.WithLabelValues("miss").Inc()
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.messages.Describe(ch)
+	c.certFetch.Describe(ch)
+	c.signatureVerify.Describe(ch)
+	c.certCache.Describe(ch)
+	c.certCacheHitRatio.Describe(ch)
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.messages.Collect(ch)
+	c.certFetch.Collect(ch)
+	c.signatureVerify.Collect(ch)
+	c.certCache.Collect(ch)
+	c.certCacheHitRatio.Collect(ch)
+}
+
+func (c *Collector) hitRatio() float64 {
+	hits := atomic.LoadUint64(&c.hits)
+	total := hits + atomic.LoadUint64(&c.misses)
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
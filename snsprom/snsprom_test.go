@@ -0,0 +1,55 @@
+package snsprom
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	c := NewCollector()
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatal(err)
+	}
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+	c.ObserveMessage(topicARN, sns.MessageTypeNotification, sns.OutcomeOK)
+	c.ObserveMessage(topicARN, sns.MessageTypeNotification, sns.OutcomeOK)
+	c.ObserveMessage(topicARN, sns.MessageTypeNotification, sns.OutcomeBadSignature)
+	c.ObserveCertFetch(10*time.Millisecond, nil)
+	c.ObserveSignatureVerification(time.Millisecond, errors.New("failed"))
+	c.ObserveCertCache(false)
+	c.ObserveCertCache(true)
+	c.ObserveCertCache(true)
+	c.ObserveCertCache(true)
+
+	want := `
+# HELP sns_messages_total Number of SNS messages received, by topic ARN, message type and outcome.
+# TYPE sns_messages_total counter
+sns_messages_total{outcome="bad-signature",topic_arn="arn:aws:sns:us-west-2:123456789012:MyTopic",type="Notification"} 1
+sns_messages_total{outcome="ok",topic_arn="arn:aws:sns:us-west-2:123456789012:MyTopic",type="Notification"} 2
+# HELP sns_cert_cache_lookups_total Number of signing cert cache lookups, by result.
+# TYPE sns_cert_cache_lookups_total counter
+sns_cert_cache_lookups_total{result="hit"} 3
+sns_cert_cache_lookups_total{result="miss"} 1
+# HELP sns_cert_cache_hit_ratio Ratio of signing cert cache lookups that were hits.
+# TYPE sns_cert_cache_hit_ratio gauge
+sns_cert_cache_hit_ratio 0.75
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want),
+		"sns_messages_total", "sns_cert_cache_lookups_total", "sns_cert_cache_hit_ratio"); err != nil {
+		t.Error(err)
+	}
+
+	if n := testutil.CollectAndCount(c, "sns_cert_fetch_duration_seconds", "sns_signature_verification_duration_seconds"); n != 2 {
+		t.Errorf("histograms = %d, want 2", n)
+	}
+}
@@ -0,0 +1,3 @@
+// Package snsslog logs the decisions of the sns middleware with log/slog.
+// It requires Go 1.21 or later.
+package snsslog
@@ -0,0 +1,47 @@
+//go:build go1.21
+
+package snsslog
+
+import (
+	"context"
+	"log/slog"
+
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+)
+
+var _ sns.Logger = (*Logger)(nil)
+
+type Logger struct {
+	logger *slog.Logger
+}
+
+// New returns an sns.Logger writing to l. Accepted messages are logged at
+// Info, rejected ones at Warn and internal failures at Error.
+func New(l *slog.Logger) *Logger {
+	return &Logger{logger: l}
+}
+
+func (l *Logger) Log(ctx context.Context, rec sns.LogRecord) {
+	attrs := []slog.Attr{
+		slog.String("message_id", rec.MessageId),
+		slog.String("topic_arn", rec.TopicArn),
+		slog.String("type", rec.MessageType.String()),
+		slog.String("outcome", string(rec.Outcome)),
+		slog.Duration("latency", rec.Latency),
+	}
+	if rec.Err != "" {
+		attrs = append(attrs, slog.String("error", rec.Err))
+	}
+	l.logger.LogAttrs(ctx, level(rec.Outcome), "sns message", attrs...)
+}
+
+func level(outcome sns.Outcome) slog.Level {
+	switch outcome {
+	case sns.OutcomeOK, sns.OutcomeDeferred:
+		return slog.LevelInfo
+	case sns.OutcomeConfirmFailed, sns.OutcomeUnavailable, sns.OutcomeError:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
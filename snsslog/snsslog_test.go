@@ -0,0 +1,89 @@
+//go:build go1.21
+
+package snsslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/snstest"
+)
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		body        string
+		wantLevel   string
+		wantOutcome string
+		wantError   string
+	}{
+		{
+			name:        "it logs accepted messages at info",
+			body:        snstest.NotificationBody,
+			wantLevel:   "INFO",
+			wantOutcome: "ok",
+		},
+		{
+			name:        "it logs rejected messages at warn",
+			body:        snstest.TamperedNotificationBody,
+			wantLevel:   "WARN",
+			wantOutcome: "bad-signature",
+			wantError:   sns.ErrInvalidSignature.Error(),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			logger := New(slog.New(slog.NewJSONHandler(buf, nil)))
+			m := sns.NewMiddleware(sns.WithClient(snstest.NewClient(t)), sns.WithLogger(logger))
+
+			w := httptest.NewRecorder()
+			m.Handler(snstest.TopicARN)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, snstest.NewRequest(tt.body))
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("log %q: %v", buf.String(), err)
+			}
+			want := map[string]interface{}{
+				"level":      tt.wantLevel,
+				"msg":        "sns message",
+				"message_id": "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+				"topic_arn":  snstest.TopicARN,
+				"type":       "Notification",
+				"outcome":    tt.wantOutcome,
+			}
+			for k, v := range want {
+				if got[k] != v {
+					t.Errorf("%s = %v, want %v", k, got[k], v)
+				}
+			}
+			if _, ok := got["latency"]; !ok {
+				t.Errorf("latency not logged")
+			}
+			if e, _ := got["error"].(string); e != tt.wantError {
+				t.Errorf("error = %q, want %q", e, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestLogger_Level(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	New(slog.New(slog.NewTextHandler(buf, nil))).Log(context.Background(), sns.LogRecord{Outcome: sns.OutcomeConfirmFailed})
+	if !bytes.Contains(buf.Bytes(), []byte("level=ERROR")) {
+		t.Errorf("log = %q, want level=ERROR", buf.String())
+	}
+}
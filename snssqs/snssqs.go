@@ -0,0 +1,139 @@
+// Package snssqs verifies SNS notifications that are delivered to SQS without
+// raw message delivery, where each SQS message body is the SNS envelope.
+package snssqs
+
+import (
+	"context"
+	"time"
+
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+)
+
+const defaultErrorBackoff = time.Second
+
+// Message is an SQS message as returned by ReceiveMessage.
+type Message struct {
+	MessageId     string
+	ReceiptHandle string
+	Body          string
+}
+
+// Queue is the subset of the SQS API used by Consumer. ReceiveMessages is
+// expected to long poll and should return when ctx is done.
+type Queue interface {
+	ReceiveMessages(ctx context.Context) ([]Message, error)
+	DeleteMessage(ctx context.Context, receiptHandle string) error
+}
+
+// Verify verifies an SQS message body with v, see sns.VerifyWith, and returns
+// the Notification in it. Other message types return
+// sns.ErrUnexpectedMessageType.
+func Verify(ctx context.Context, v sns.Verifier, body []byte) (sns.Notification, error) {
+	msg, err := sns.VerifyWith(ctx, v, body)
+	if err != nil {
+		return sns.Notification{}, err
+	}
+	n, ok := msg.(sns.Notification)
+	if !ok {
+		return sns.Notification{}, sns.ErrUnexpectedMessageType
+	}
+	return n, nil
+}
+
+type Consumer struct {
+	queue        Queue
+	verifier     sns.Verifier
+	snsTopicARN  string
+	handler      sns.NotificationHandler
+	onError      func(msg Message, err error)
+	errorBackoff time.Duration
+}
+
+type Option func(*Consumer)
+
+// WithClient sets the Client used to verify messages.
+func WithClient(c *sns.Client) Option {
+	return func(co *Consumer) {
+		co.verifier = c
+	}
+}
+
+// WithVerifier sets the Verifier used to verify messages.
+func WithVerifier(v sns.Verifier) Option {
+	return func(co *Consumer) {
+		co.verifier = v
+	}
+}
+
+// WithErrorHandler is called for messages that fail verification or handling,
+// and with an empty Message when receiving fails.
+func WithErrorHandler(f func(msg Message, err error)) Option {
+	return func(co *Consumer) {
+		co.onError = f
+	}
+}
+
+// WithErrorBackoff sets how long Run waits after ReceiveMessages fails.
+func WithErrorBackoff(d time.Duration) Option {
+	return func(co *Consumer) {
+		co.errorBackoff = d
+	}
+}
+
+// NewConsumer returns a Consumer that verifies messages for snsTopicARN received
+// from q and passes each Notification to h.
+func NewConsumer(q Queue, snsTopicARN string, h sns.NotificationHandler, opts ...Option) *Consumer {
+	c := &Consumer{
+		queue:        q,
+		verifier:     sns.NewClient(),
+		snsTopicARN:  snsTopicARN,
+		handler:      h,
+		onError:      func(Message, error) {},
+		errorBackoff: defaultErrorBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run polls the queue until ctx is done. Messages are deleted only after the
+// handler succeeds; messages that fail are left for SQS to redeliver or move to
+// a dead-letter queue.
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		msgs, err := c.queue.ReceiveMessages(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			c.onError(Message{}, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.errorBackoff):
+			}
+			continue
+		}
+
+		for _, msg := range msgs {
+			if err := c.process(ctx, msg); err != nil {
+				c.onError(msg, err)
+			}
+		}
+	}
+}
+
+func (c *Consumer) process(ctx context.Context, m Message) error {
+	msg, err := Verify(ctx, c.verifier, []byte(m.Body))
+	if err != nil {
+		return err
+	}
+	if msg.TopicArn != c.snsTopicARN {
+		return sns.ErrInvalidTopicArn
+	}
+	if err := c.handler.HandleNotification(sns.NewContext(ctx, msg), msg); err != nil {
+		return err
+	}
+	return c.queue.DeleteMessage(ctx, m.ReceiptHandle)
+}
@@ -0,0 +1,190 @@
+package snssqs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/snstest"
+)
+
+var _ Queue = (*fakeQueue)(nil)
+
+// fakeQueue returns its messages on the first receive and calls done once empty.
+type fakeQueue struct {
+	mu       sync.Mutex
+	messages []Message
+	deleted  []string
+	done     func()
+}
+
+func (q *fakeQueue) ReceiveMessages(ctx context.Context) ([]Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.messages) == 0 {
+		q.done()
+		return nil, ctx.Err()
+	}
+	msgs := q.messages
+	q.messages = nil
+	return msgs, nil
+}
+
+func (q *fakeQueue) DeleteMessage(ctx context.Context, receiptHandle string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.deleted = append(q.deleted, receiptHandle)
+	return nil
+}
+
+// denyVerifier accepts every cert URL and rejects every signature.
+type denyVerifier struct{}
+
+func (denyVerifier) ValidateCertURL(certURL string) error {
+	return nil
+}
+
+func (denyVerifier) CheckSignature(ms sns.MessageSignature) error {
+	return sns.ErrInvalidSignature
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		body     string
+		verifier sns.Verifier
+		want     error
+		wantErr  bool
+	}{
+		"success": {
+			body: snstest.NotificationBody,
+		},
+		"invalid signature": {
+			body:    snstest.TamperedNotificationBody,
+			want:    sns.ErrInvalidSignature,
+			wantErr: true,
+		},
+		"unexpected type": {
+			body:    snstest.SubscriptionConfirmationBody,
+			want:    sns.ErrUnexpectedMessageType,
+			wantErr: true,
+		},
+		"verifier": {
+			body:     snstest.NotificationBody,
+			verifier: denyVerifier{},
+			want:     sns.ErrInvalidSignature,
+			wantErr:  true,
+		},
+		"raw message delivery": {
+			body:    "Hello world!",
+			wantErr: true,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			v := tt.verifier
+			if v == nil {
+				v = snstest.NewClient(t)
+			}
+			msg, err := Verify(context.Background(), v, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+			if err == nil && msg.Message != "Hello world!" {
+				t.Errorf("Message = %v, want %v", msg.Message, "Hello world!")
+			}
+		})
+	}
+}
+
+func TestConsumer_Run(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		topicARN    string
+		messages    []Message
+		handleErr   error
+		wantDeleted []string
+		wantErrors  []error
+	}{
+		"success": {
+			topicARN: snstest.TopicARN,
+			messages: []Message{
+				{MessageId: "1", ReceiptHandle: "rh-1", Body: snstest.NotificationBody},
+			},
+			wantDeleted: []string{"rh-1"},
+		},
+		"invalid signature is not deleted": {
+			topicARN: snstest.TopicARN,
+			messages: []Message{
+				{MessageId: "1", ReceiptHandle: "rh-1", Body: snstest.TamperedNotificationBody},
+				{MessageId: "2", ReceiptHandle: "rh-2", Body: snstest.NotificationBody},
+			},
+			wantDeleted: []string{"rh-2"},
+			wantErrors:  []error{sns.ErrInvalidSignature},
+		},
+		"invalid topic arn is not deleted": {
+			topicARN: "arn:aws:sns:us-west-2:123456789012:OtherTopic",
+			messages: []Message{
+				{MessageId: "1", ReceiptHandle: "rh-1", Body: snstest.NotificationBody},
+			},
+			wantErrors: []error{sns.ErrInvalidTopicArn},
+		},
+		"handler error is not deleted": {
+			topicARN: snstest.TopicARN,
+			messages: []Message{
+				{MessageId: "1", ReceiptHandle: "rh-1", Body: snstest.NotificationBody},
+			},
+			handleErr:  errors.New("failed"),
+			wantErrors: []error{errors.New("failed")},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			q := &fakeQueue{messages: tt.messages, done: cancel}
+			var gotErrors []error
+			c := NewConsumer(q, tt.topicARN, sns.NotificationHandlerFunc(func(ctx context.Context, msg sns.Notification) error {
+				return tt.handleErr
+			}), WithClient(snstest.NewClient(t)), WithErrorHandler(func(msg Message, err error) {
+				gotErrors = append(gotErrors, err)
+			}))
+
+			if err := c.Run(ctx); err != context.Canceled {
+				t.Errorf("Run() = %v, want %v", err, context.Canceled)
+			}
+			if len(q.deleted) != len(tt.wantDeleted) {
+				t.Fatalf("deleted = %v, want %v", q.deleted, tt.wantDeleted)
+			}
+			for i := range q.deleted {
+				if q.deleted[i] != tt.wantDeleted[i] {
+					t.Errorf("deleted = %v, want %v", q.deleted, tt.wantDeleted)
+				}
+			}
+			if len(gotErrors) != len(tt.wantErrors) {
+				t.Fatalf("errors = %v, want %v", gotErrors, tt.wantErrors)
+			}
+			for i := range gotErrors {
+				if gotErrors[i].Error() != tt.wantErrors[i].Error() {
+					t.Errorf("errors = %v, want %v", gotErrors, tt.wantErrors)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package sns
+
+import "context"
+
+// Stage is a step of the middleware pipeline traced by a Tracer.
+type Stage string
+
+const (
+	StageDecode          Stage = "decode"
+	StageValidateCertURL Stage = "validate-cert-url"
+	StageFetchCert       Stage = "fetch-cert"
+	StageCheckSignature  Stage = "check-signature"
+	StageConfirm         Stage = "confirm"
+	StageHandler         Stage = "handler"
+)
+
+// SpanInfo describes the message a span is started for. Fields are empty when
+// they are not known at that stage.
+type SpanInfo struct {
+	TopicArn    string
+	MessageId   string
+	MessageType MessageType
+}
+
+type Span interface {
+	End(err error)
+}
+
+// Tracer starts spans for the stages of the middleware pipeline. See the
+// snsotel package for an OpenTelemetry implementation.
+type Tracer interface {
+	Start(ctx context.Context, stage Stage, info SpanInfo) (context.Context, Span)
+	// Extract returns ctx carrying the trace context found in the
+	// MessageAttributes of msg, if any. The handler span is started from it.
+	Extract(ctx context.Context, msg Notification) context.Context
+}
+
+// WithTracer traces the stages of the pipeline with t.
+func WithTracer(t Tracer) Option {
+	return func(m *Middleware) {
+		m.tracer = t
+	}
+}
+
+// WithClientTracer traces cert fetches with t.
+func WithClientTracer(t Tracer) ClientOption {
+	return func(c *Client) {
+		c.tracer = t
+	}
+}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, stage Stage, info SpanInfo) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+func (nopTracer) Extract(ctx context.Context, msg Notification) context.Context {
+	return ctx
+}
+
+type nopSpan struct{}
+
+func (nopSpan) End(error) {}
+
+// trace runs f in a span for stage.
+func trace(ctx context.Context, t Tracer, stage Stage, info SpanInfo, f func(ctx context.Context) error) error {
+	ctx, span := t.Start(ctx, stage, info)
+	err := f(ctx)
+	span.End(err)
+	return err
+}
@@ -0,0 +1,102 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type mockTracer struct {
+	mu     sync.Mutex
+	stages []Stage
+	errs   []error
+}
+
+func (t *mockTracer) Start(ctx context.Context, stage Stage, info SpanInfo) (context.Context, Span) {
+	return ctx, &mockSpan{tracer: t, stage: stage}
+}
+
+func (t *mockTracer) Extract(ctx context.Context, msg Notification) context.Context {
+	return ctx
+}
+
+type mockSpan struct {
+	tracer *mockTracer
+	stage  Stage
+}
+
+func (s *mockSpan) End(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.stages = append(s.tracer.stages, s.stage)
+	s.tracer.errs = append(s.tracer.errs, err)
+}
+
+func TestMiddleware_Subscribe_Tracer(t *testing.T) {
+	t.Parallel()
+
+	topicARN := "arn:aws:sns:us-west-2:123456789012:MyTopic"
+
+	tests := map[string]struct {
+		messageType  string
+		msg          interface{}
+		signatureErr error
+		want         []Stage
+	}{
+		"Notification": {
+			messageType: "Notification",
+			msg:         Notification{Type: "Notification", TopicArn: topicARN},
+			want:        []Stage{StageDecode, StageValidateCertURL, StageCheckSignature, StageHandler},
+		},
+		"SubscriptionConfirmation": {
+			messageType: "SubscriptionConfirmation",
+			msg:         SubscriptionConfirmation{Type: "SubscriptionConfirmation", TopicArn: topicARN},
+			want:        []Stage{StageDecode, StageValidateCertURL, StageCheckSignature, StageConfirm},
+		},
+		"CheckSignature failed": {
+			messageType:  "Notification",
+			msg:          Notification{Type: "Notification", TopicArn: topicARN},
+			signatureErr: ErrInvalidSignature,
+			want:         []Stage{StageDecode, StageValidateCertURL, StageCheckSignature},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tracer := &mockTracer{}
+			m := NewMiddleware(WithTracer(tracer))
+			setSubscriber(m, &mockSubscriber{
+				ExpectConfirmSubscription: func(msg SubscriptionConfirmation) (*ConfirmSubscriptionResult, error) {
+					return &ConfirmSubscriptionResult{}, nil
+				},
+				ExpectValidateCertURL: func(certURL string) error {
+					return nil
+				},
+				ExpectCheckSignature: func(ms MessageSignature) error {
+					return tt.signatureErr
+				},
+			})
+
+			b, _ := json.Marshal(tt.msg)
+			req := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+			req.Header.Set(XAmzSnsTopicArn, topicARN)
+			req.Header.Set(XAmzSnsMessageType, tt.messageType)
+			w := httptest.NewRecorder()
+			m.Subscribe(topicARN)(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+
+			if !reflect.DeepEqual(tracer.stages, tt.want) {
+				t.Errorf("stages = %v, want %v", tracer.stages, tt.want)
+			}
+			if last := tracer.errs[len(tracer.errs)-1]; tt.signatureErr != nil && last != tt.signatureErr {
+				t.Errorf("last span error = %v, want %v", last, tt.signatureErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package sns
+
+import "context"
+
+var defaultClient = NewClient()
+
+// Verify verifies envelope with a default Client. See Client.Verify.
+func Verify(ctx context.Context, envelope []byte) (Message, error) {
+	return defaultClient.Verify(ctx, envelope)
+}
+
+// Verify decodes envelope with DecodeMessage, validates the signing cert URL
+// and checks the signature.
+func (c *Client) Verify(ctx context.Context, envelope []byte) (Message, error) {
+	return VerifyWith(ctx, c, envelope)
+}
+
+// VerifyWith is Client.Verify with any Verifier.
+func VerifyWith(ctx context.Context, v Verifier, envelope []byte) (Message, error) {
+	msg, err := DecodeMessage(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := msg.MessageSignature()
+	if err := v.ValidateCertURL(ms.SigningCertURL); err != nil {
+		return nil, err
+	}
+	if cv, ok := v.(contextVerifier); ok {
+		err = cv.CheckSignatureContext(ctx, ms)
+	} else {
+		err = v.CheckSignature(ms)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
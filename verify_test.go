@@ -0,0 +1,95 @@
+package sns_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	sns "github.com/yasszu/aws-sns-subscrube-https-go"
+	"github.com/yasszu/aws-sns-subscrube-https-go/internal/snstest"
+)
+
+func TestClient_Verify(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		envelope string
+		want     reflect.Type
+		wantErr  error
+	}{
+		"Notification": {
+			envelope: snstest.NotificationBody,
+			want:     reflect.TypeOf(sns.Notification{}),
+		},
+		"SubscriptionConfirmation": {
+			envelope: snstest.SubscriptionConfirmationBody,
+			want:     reflect.TypeOf(sns.SubscriptionConfirmation{}),
+		},
+		"invalid signature": {
+			envelope: snstest.TamperedNotificationBody,
+			wantErr:  sns.ErrInvalidSignature,
+		},
+		"invalid cert url": {
+			envelope: `{"Type":"Notification","SignatureVersion":"1","SigningCertURL":"https://example.com/cert.pem"}`,
+			wantErr:  sns.ErrInvalidCertURLHost,
+		},
+		"unknown type": {
+			envelope: `{"Type":"Unknown"}`,
+			wantErr:  sns.ErrUnexpectedMessageType,
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := snstest.NewClient(t).Verify(context.Background(), []byte(tt.envelope))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if reflect.TypeOf(got) != tt.want {
+				t.Errorf("Verify() = %T, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("bad json", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := snstest.NewClient(t).Verify(context.Background(), []byte(`{`)); err == nil {
+			t.Error("Verify() error = nil, want an error")
+		}
+	})
+}
+
+// verifierFunc checks signatures with f and accepts every cert URL.
+type verifierFunc func(ms sns.MessageSignature) error
+
+func (f verifierFunc) ValidateCertURL(certURL string) error {
+	return nil
+}
+
+func (f verifierFunc) CheckSignature(ms sns.MessageSignature) error {
+	return f(ms)
+}
+
+func TestVerifyWith(t *testing.T) {
+	t.Parallel()
+
+	var signed string
+	v := verifierFunc(func(ms sns.MessageSignature) error {
+		signed = string(ms.Signed)
+		return sns.ErrInvalidSignature
+	})
+	if _, err := sns.VerifyWith(context.Background(), v, []byte(snstest.NotificationBody)); !errors.Is(err, sns.ErrInvalidSignature) {
+		t.Errorf("VerifyWith() error = %v, want %v", err, sns.ErrInvalidSignature)
+	}
+	if !strings.Contains(signed, "Hello world!") {
+		t.Errorf("Signed = %q, want the Notification", signed)
+	}
+}